@@ -0,0 +1,94 @@
+// Command rotatelogs reads log lines from the standard input, and
+// writes them to files that are rotated according to a strftime
+// pattern. It can be used to rotate logs of programs that only know
+// how to write to stdout or a pipe, such as Apache's CustomLog:
+//
+//	CustomLog "|rotatelogs -max-age 14d /var/log/httpd/access_log.%Y%m%d" combined
+//
+// Run `rotatelogs -h` for the list of flags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+)
+
+func main() {
+	if err := _main(); err != nil {
+		fmt.Fprintf(os.Stderr, "rotatelogs: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func _main() error {
+	var cfg rotatelogs.Config
+	var envPrefix string
+
+	fs := flag.NewFlagSet("rotatelogs", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: rotatelogs [flags] PATTERN\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&envPrefix, "env", "", "load configuration from environment variables with the given prefix (e.g. ROTATELOGS)")
+	fs.StringVar(&cfg.LinkName, "link", "", "path of the symlink to the current log file")
+	fs.StringVar(&cfg.MaxAge, "max-age", "", `max age of log files before they are purged (e.g. "14d")`)
+	fs.StringVar(&cfg.RotationTime, "rotation-time", "", `time between rotations (e.g. "1h", "1d")`)
+	fs.StringVar(&cfg.RotationSize, "rotation-size", "", `log file size that triggers a rotation (e.g. "512MiB")`)
+	fs.UintVar(&cfg.RotationCount, "rotation-count", 0, "number of log files to keep")
+	fs.StringVar(&cfg.Location, "location", "", `time zone used to generate file names (e.g. "UTC")`)
+	fs.StringVar(&cfg.RenameOnRotate, "rename-on-rotate", "", "always write to this file, and rename it upon rotation")
+	fs.StringVar(&cfg.CopyTruncate, "copy-truncate", "", "always write to this file, and copy and truncate it upon rotation")
+	fs.StringVar(&cfg.FileMode, "file-mode", "", `permission bits of log files, in octal (e.g. "0600")`)
+	fs.StringVar(&cfg.SyncPolicy, "sync", "", `fsync policy: "never", "every-write", "on-rotate", or an interval`)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+
+		return err
+	}
+
+	if envPrefix != "" {
+		if err := cfg.LoadEnv(envPrefix); err != nil {
+			return err
+		}
+	}
+
+	switch fs.NArg() {
+	case 0:
+		if cfg.Pattern == "" {
+			fs.Usage()
+
+			return fmt.Errorf("PATTERN is required")
+		}
+	case 1:
+		cfg.Pattern = fs.Arg(0)
+	default:
+		fs.Usage()
+
+		return fmt.Errorf("too many arguments")
+	}
+
+	rl, err := rotatelogs.NewFromConfig(&cfg)
+	if err != nil {
+		return err
+	}
+
+	_, copyErr := io.Copy(rl, os.Stdin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := rl.Shutdown(ctx); err != nil && copyErr == nil {
+		return err
+	}
+
+	return copyErr
+}
@@ -0,0 +1,277 @@
+package rotatelogs
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config is a declarative representation of the arguments to New,
+// suitable for loading from configuration files (e.g. JSON or YAML)
+// or from environment variables (see LoadEnv).
+//
+// Durations are given as strings accepted by ParseRetention (e.g.
+// "24h" or "14d"), and sizes as strings accepted by ParseSize (e.g.
+// "100MB" or "512MiB").
+// Empty fields leave the corresponding option unset.
+type Config struct {
+	// Pattern is the strftime pattern of the log file names. Required.
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// LinkName corresponds to WithLinkName
+	LinkName string `json:"link_name,omitempty" yaml:"link_name,omitempty"`
+	// MaxAge corresponds to WithMaxAge. A negative value disables it,
+	// but only together with RotationCount: otherwise the default
+	// max age of 7 days applies
+	MaxAge string `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	// RotationTime corresponds to WithRotationTime
+	RotationTime string `json:"rotation_time,omitempty" yaml:"rotation_time,omitempty"`
+	// RotationSize corresponds to WithRotationSize
+	RotationSize string `json:"rotation_size,omitempty" yaml:"rotation_size,omitempty"`
+	// RotationCount corresponds to WithRotationCount
+	RotationCount uint `json:"rotation_count,omitempty" yaml:"rotation_count,omitempty"`
+	// Location is the name of the time zone used to generate file
+	// names, such as "UTC", "Local", or "Asia/Tokyo"
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	// ForceNewFile corresponds to ForceNewFile
+	ForceNewFile bool `json:"force_new_file,omitempty" yaml:"force_new_file,omitempty"`
+	// RenameOnRotate corresponds to WithRenameOnRotate
+	RenameOnRotate string `json:"rename_on_rotate,omitempty" yaml:"rename_on_rotate,omitempty"`
+	// CopyTruncate corresponds to WithCopyTruncate
+	CopyTruncate string `json:"copy_truncate,omitempty" yaml:"copy_truncate,omitempty"`
+	// GenerationFormat is one of "suffix", "before-ext", or
+	// "zero-padded" (see WithGenerationFormat)
+	GenerationFormat string `json:"generation_format,omitempty" yaml:"generation_format,omitempty"`
+	// GenerationWidth is the width used by the "zero-padded"
+	// generation format. The default is 3
+	GenerationWidth int `json:"generation_width,omitempty" yaml:"generation_width,omitempty"`
+	// FileMode corresponds to WithFileMode, in octal (e.g. "0600")
+	FileMode string `json:"file_mode,omitempty" yaml:"file_mode,omitempty"`
+	// DirMode corresponds to WithDirMode, in octal (e.g. "0700")
+	DirMode string `json:"dir_mode,omitempty" yaml:"dir_mode,omitempty"`
+	// Owner corresponds to WithOwner, in the form "uid:gid"
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// FileWatch corresponds to WithFileWatch
+	FileWatch string `json:"file_watch,omitempty" yaml:"file_watch,omitempty"`
+	// ProcessLock corresponds to WithProcessLock
+	ProcessLock string `json:"process_lock,omitempty" yaml:"process_lock,omitempty"`
+	// StaleLockTimeout corresponds to WithStaleLockTimeout
+	StaleLockTimeout string `json:"stale_lock_timeout,omitempty" yaml:"stale_lock_timeout,omitempty"`
+	// SyncPolicy is one of "never", "every-write", "on-rotate", or
+	// a duration for SyncInterval (see WithSyncPolicy)
+	SyncPolicy string `json:"sync_policy,omitempty" yaml:"sync_policy,omitempty"`
+}
+
+// NewFromConfig creates a new RotateLogs object from the given Config.
+// Additional options may be passed, which are applied after those
+// specified by the Config.
+func NewFromConfig(c *Config, options ...Option) (*RotateLogs, error) {
+	if c.Pattern == "" {
+		return nil, errors.New(`pattern must not be empty`)
+	}
+
+	configOptions, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(c.Pattern, append(configOptions, options...)...)
+}
+
+// Options converts the Config into a list of Options that can be
+// passed to New, along with c.Pattern
+func (c *Config) Options() ([]Option, error) {
+	var options []Option
+
+	if c.LinkName != "" {
+		options = append(options, WithLinkName(c.LinkName))
+	}
+
+	for _, v := range []struct {
+		name  string
+		value string
+		fn    func(time.Duration) Option
+	}{
+		{name: "max_age", value: c.MaxAge, fn: WithMaxAge},
+		{name: "rotation_time", value: c.RotationTime, fn: WithRotationTime},
+		{name: "file_watch", value: c.FileWatch, fn: WithFileWatch},
+		{name: "stale_lock_timeout", value: c.StaleLockTimeout, fn: WithStaleLockTimeout},
+	} {
+		if v.value == "" {
+			continue
+		}
+
+		d, err := ParseRetention(v.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, `invalid value for %s`, v.name)
+		}
+		options = append(options, v.fn(d))
+	}
+
+	if c.RotationSize != "" {
+		size, err := ParseSize(c.RotationSize)
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid value for rotation_size`)
+		}
+		options = append(options, WithRotationSize(size))
+	}
+
+	if c.RotationCount > 0 {
+		options = append(options, WithRotationCount(c.RotationCount))
+	}
+
+	switch c.Location {
+	case "":
+	case "Local":
+		options = append(options, WithClock(Local))
+	case "UTC":
+		options = append(options, WithClock(UTC))
+	default:
+		loc, err := time.LoadLocation(c.Location)
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid value for location`)
+		}
+		options = append(options, WithLocation(loc))
+	}
+
+	if c.ForceNewFile {
+		options = append(options, ForceNewFile())
+	}
+
+	if c.RenameOnRotate != "" {
+		options = append(options, WithRenameOnRotate(c.RenameOnRotate))
+	}
+
+	if c.CopyTruncate != "" {
+		options = append(options, WithCopyTruncate(c.CopyTruncate))
+	}
+
+	switch c.GenerationFormat {
+	case "":
+	case "suffix":
+		options = append(options, WithGenerationFormat(GenerationSuffix))
+	case "before-ext":
+		options = append(options, WithGenerationFormat(GenerationBeforeExt))
+	case "zero-padded":
+		width := c.GenerationWidth
+		if width <= 0 {
+			width = 3
+		}
+		options = append(options, WithGenerationFormat(GenerationZeroPadded(width)))
+	default:
+		return nil, errors.Errorf(`invalid value for generation_format: %q`, c.GenerationFormat)
+	}
+
+	for _, v := range []struct {
+		name  string
+		value string
+		fn    func(os.FileMode) Option
+	}{
+		{name: "file_mode", value: c.FileMode, fn: WithFileMode},
+		{name: "dir_mode", value: c.DirMode, fn: WithDirMode},
+	} {
+		if v.value == "" {
+			continue
+		}
+
+		mode, err := strconv.ParseUint(v.value, 8, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, `invalid value for %s`, v.name)
+		}
+		options = append(options, v.fn(os.FileMode(mode)))
+	}
+
+	if c.Owner != "" {
+		ids := strings.SplitN(c.Owner, ":", 2)
+		if len(ids) != 2 {
+			return nil, errors.Errorf(`invalid value for owner: %q (expected "uid:gid")`, c.Owner)
+		}
+
+		uid, err := strconv.Atoi(ids[0])
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid uid for owner`)
+		}
+		gid, err := strconv.Atoi(ids[1])
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid gid for owner`)
+		}
+		options = append(options, WithOwner(uid, gid))
+	}
+
+	if c.ProcessLock != "" {
+		options = append(options, WithProcessLock(c.ProcessLock))
+	}
+
+	switch c.SyncPolicy {
+	case "":
+	case "never":
+		options = append(options, WithSyncPolicy(SyncNever))
+	case "every-write":
+		options = append(options, WithSyncPolicy(SyncEveryWrite))
+	case "on-rotate":
+		options = append(options, WithSyncPolicy(SyncOnRotate))
+	default:
+		d, err := ParseRetention(c.SyncPolicy)
+		if err != nil {
+			return nil, errors.Errorf(`invalid value for sync_policy: %q`, c.SyncPolicy)
+		}
+		options = append(options, WithSyncPolicy(SyncInterval(d)))
+	}
+
+	return options, nil
+}
+
+// LoadEnv sets the fields of the Config from environment variables.
+// The name of the variable for each field is the upper cased JSON
+// field name, prefixed by the given prefix and an underscore. For
+// example, with the prefix "MYAPP_LOG", the pattern is read from
+// MYAPP_LOG_PATTERN, and the max age from MYAPP_LOG_MAX_AGE.
+//
+// Fields whose variables are not set are left untouched, so that
+// environment variables can override values loaded from elsewhere.
+func (c *Config) LoadEnv(prefix string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	rv := reflect.ValueOf(c).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := strings.SplitN(rt.Field(i).Tag.Get("json"), ",", 2)[0]
+		key := prefix + strings.ToUpper(name)
+
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+
+		field := rv.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.Wrapf(err, `invalid value for %s`, key)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.ParseInt(value, 10, 0)
+			if err != nil {
+				return errors.Wrapf(err, `invalid value for %s`, key)
+			}
+			field.SetInt(n)
+		case reflect.Uint:
+			n, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				return errors.Wrapf(err, `invalid value for %s`, key)
+			}
+			field.SetUint(n)
+		}
+	}
+
+	return nil
+}
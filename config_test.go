@@ -0,0 +1,112 @@
+package rotatelogs_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-config")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("From JSON", func(t *testing.T) {
+		src := `{
+			"pattern": "` + filepath.Join(dir, "json.%Y%m%d.log") + `",
+			"link_name": "` + filepath.Join(dir, "json.log") + `",
+			"max_age": "168h",
+			"rotation_time": "1h",
+			"rotation_size": "100MB",
+			"location": "UTC",
+			"generation_format": "before-ext",
+			"file_mode": "0600",
+			"sync_policy": "on-rotate"
+		}`
+
+		var cfg rotatelogs.Config
+		if !assert.NoError(t, json.Unmarshal([]byte(src), &cfg), "json.Unmarshal should succeed") {
+			return
+		}
+
+		rl, err := rotatelogs.NewFromConfig(&cfg)
+		if !assert.NoError(t, err, "rotatelogs.NewFromConfig should succeed") {
+			return
+		}
+		defer rl.Close()
+
+		if _, err := rl.Write([]byte("Hello, World!")); !assert.NoError(t, err, "rl.Write should succeed") {
+			return
+		}
+
+		fi, err := os.Stat(rl.CurrentFileName())
+		if !assert.NoError(t, err, "os.Stat should succeed") {
+			return
+		}
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "file mode should match")
+
+		linkDest, err := os.Readlink(filepath.Join(dir, "json.log"))
+		if !assert.NoError(t, err, "os.Readlink should succeed") {
+			return
+		}
+		assert.Equal(t, filepath.Base(rl.CurrentFileName()), linkDest, "symlink should point to the current file")
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		for _, cfg := range []rotatelogs.Config{
+			{},
+			{Pattern: "log", MaxAge: "a week"},
+			{Pattern: "log", RotationSize: "100 bananas"},
+			{Pattern: "log", Location: "Nowhere/Special"},
+			{Pattern: "log", GenerationFormat: "roman"},
+			{Pattern: "log", FileMode: "rw-------"},
+			{Pattern: "log", Owner: "1000"},
+			{Pattern: "log", SyncPolicy: "sometimes"},
+			{Pattern: "log", MaxAge: "1h", RotationCount: 1},
+		} {
+			cfg := cfg
+			_, err := rotatelogs.NewFromConfig(&cfg)
+			assert.Error(t, err, "rotatelogs.NewFromConfig should fail for %#v", cfg)
+		}
+	})
+
+	t.Run("From environment", func(t *testing.T) {
+		env := map[string]string{
+			"RL_TEST_PATTERN":        filepath.Join(dir, "env.%Y%m%d.log"),
+			"RL_TEST_ROTATION_COUNT": "7",
+			"RL_TEST_MAX_AGE":        "-1s",
+			"RL_TEST_FORCE_NEW_FILE": "true",
+		}
+		for k, v := range env {
+			os.Setenv(k, v)
+			defer os.Unsetenv(k)
+		}
+
+		cfg := rotatelogs.Config{LinkName: filepath.Join(dir, "env.log")}
+		if !assert.NoError(t, cfg.LoadEnv("RL_TEST"), "cfg.LoadEnv should succeed") {
+			return
+		}
+
+		assert.Equal(t, env["RL_TEST_PATTERN"], cfg.Pattern, "pattern should be loaded")
+		assert.Equal(t, uint(7), cfg.RotationCount, "rotation count should be loaded")
+		assert.Equal(t, "-1s", cfg.MaxAge, "max age should be loaded")
+		assert.True(t, cfg.ForceNewFile, "force new file should be loaded")
+		assert.Equal(t, filepath.Join(dir, "env.log"), cfg.LinkName, "fields not in the environment should be left untouched")
+
+		rl, err := rotatelogs.NewFromConfig(&cfg)
+		if !assert.NoError(t, err, "rotatelogs.NewFromConfig should succeed") {
+			return
+		}
+		rl.Close()
+
+		os.Setenv("RL_TEST_ROTATION_COUNT", "many")
+		assert.Error(t, cfg.LoadEnv("RL_TEST"), "cfg.LoadEnv should fail")
+	})
+}
@@ -0,0 +1,54 @@
+package rotatelogs
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors returned (possibly wrapped) by this package. Use
+// errors.Is to check for them.
+var (
+	// ErrInvalidPattern is returned by New when the file name pattern
+	// is not a valid strftime pattern
+	ErrInvalidPattern = errors.New("rotatelogs: invalid pattern")
+
+	// ErrConflictingOptions is returned by New when options that
+	// cannot be used together are specified
+	ErrConflictingOptions = errors.New("rotatelogs: conflicting options")
+
+	// ErrUnsupported is returned by New when an option is not
+	// supported on the current platform
+	ErrUnsupported = errors.New("rotatelogs: option not supported on this platform")
+
+	// ErrRotateLocked is returned by Rotate when another rotation of
+	// the same file is in progress, as indicated by its lock file.
+	// It is usually safe to treat this error as non-fatal
+	ErrRotateLocked = errors.New("rotatelogs: rotation is locked")
+
+	// ErrClosed is returned when writing to, or otherwise operating on,
+	// a RotateLogs object that has already been closed
+	ErrClosed = errors.New("rotatelogs: use of closed RotateLogs")
+)
+
+// sentinelError attaches a sentinel error to an error, so that it
+// matches the sentinel with errors.Is, while retaining the original
+// error message and cause
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func withSentinel(sentinel, err error) error {
+	return &sentinelError{sentinel: sentinel, err: err}
+}
+
+func (e *sentinelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,56 @@
+package rotatelogs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GenerationSuffix is a GenerationFormatter that appends the generation
+// to the end of the file name, such as "foo.log.1", "foo.log.2", etc.
+// This is the default.
+var GenerationSuffix = GenerationFormatFunc(func(filename string, generation int) string {
+	return fmt.Sprintf("%s.%d", filename, generation)
+})
+
+// GenerationBeforeExt is a GenerationFormatter that inserts the generation
+// before the file name extension, such as "foo.1.log", "foo.2.log", etc.
+// File names without an extension behave like GenerationSuffix.
+var GenerationBeforeExt = GenerationFormatFunc(func(filename string, generation int) string {
+	ext := filepath.Ext(filename)
+
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(filename, ext), generation, ext)
+})
+
+// GenerationZeroPadded creates a GenerationFormatter that appends the
+// generation zero-padded to the given width, such as "foo.log.001",
+// "foo.log.002", etc.
+func GenerationZeroPadded(width int) GenerationFormatter {
+	return GenerationFormatFunc(func(filename string, generation int) string {
+		return fmt.Sprintf("%s.%0*d", filename, width, generation)
+	})
+}
+
+func (f GenerationFormatFunc) FormatGeneration(filename string, generation int) string {
+	return f(filename, generation)
+}
+
+// generationProbe is a generation that is formatted to find out where
+// a GenerationFormatter places the generation in the file name
+const generationProbe = 123456789
+
+// generationGlob converts a glob pattern that matches the file names
+// generated from the pattern to one that matches their generational
+// names. An empty string is returned if the generation can't be located
+func generationGlob(f GenerationFormatter, globPattern string) string {
+	name := f.FormatGeneration(globPattern, generationProbe)
+	probe := strconv.Itoa(generationProbe)
+	i := strings.LastIndex(name, probe)
+	if i < 0 {
+		return ""
+	}
+
+	// strip padding, if any, so that larger generations still match
+	return strings.TrimRight(name[:i], "0") + "*" + name[i+len(probe):]
+}
@@ -1,11 +1,11 @@
 module github.com/lestrrat-go/file-rotatelogs
 
-go 1.12
+go 1.13
 
 require (
 	github.com/jonboulle/clockwork v0.1.0
 	github.com/lestrrat-go/strftime v0.0.0-20180821113735-8b31f9c59b0f
-	github.com/pkg/errors v0.8.1
+	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.3.0
 	github.com/tebeka/strftime v0.1.3
 	golang.org/x/review v0.0.0-20200515044942-a2b90d2f6e29 // indirect
@@ -0,0 +1,91 @@
+package rotatelogs
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileInfo describes a log file. It is passed to the functions given to
+// WithFileHeader and WithFileFooter
+type FileInfo struct {
+	// Filename is the name of the file the header or footer is written to
+	Filename string
+	// PreviousFile is the name of the file that was written to before,
+	// if any. Only set for headers
+	PreviousFile string
+	// NextFile is the name of the file that is written to next. Only
+	// set for footers
+	NextFile string
+	// Time is the current time according to the clock of the
+	// RotateLogs object
+	Time time.Time
+}
+
+// writeHeaderNolock writes the header to fh, if fh is a new, empty file,
+// and returns the number of bytes written
+//
+// must be locked during this operation
+func (rl *RotateLogs) writeHeaderNolock(fh *os.File, filename, previousFn string) (int64, error) {
+	if rl.header == nil {
+		return 0, nil
+	}
+
+	fi, err := fh.Stat()
+	if err != nil {
+		return 0, errors.Wrapf(err, `failed to stat %s`, filename)
+	}
+	if fi.Size() > 0 {
+		// appending to an existing file, which already has a header
+		return 0, nil
+	}
+
+	header := rl.header(FileInfo{
+		Filename:     filename,
+		PreviousFile: previousFn,
+		Time:         rl.clock.Now(),
+	})
+	n, err := fh.Write(header)
+	if err != nil {
+		return int64(n), errors.Wrapf(err, `failed to write header to %s`, filename)
+	}
+	return int64(n), nil
+}
+
+// writeFooterNolock writes the footer to fh, which is about to be closed
+//
+// must be locked during this operation
+func (rl *RotateLogs) writeFooterNolock(fh *os.File, filename, nextFn string) error {
+	if rl.footer == nil || fh == nil {
+		return nil
+	}
+
+	footer := rl.footer(FileInfo{
+		Filename: filename,
+		NextFile: nextFn,
+		Time:     rl.clock.Now(),
+	})
+	if _, err := fh.Write(footer); err != nil {
+		return errors.Wrapf(err, `failed to write footer to %s`, filename)
+	}
+	return nil
+}
+
+// startFileNolock records the size of the header written to the file
+// that we have just started writing to, and reserves room for its
+// footer when the rotation size is a hard limit. As the name of the
+// next file is not known yet, the room is that of a footer with an
+// empty NextFile
+//
+// must be locked during this operation
+func (rl *RotateLogs) startFileNolock(filename string, headerSize int64) {
+	rl.headerSize = headerSize
+	rl.footerSize = 0
+	if rl.footer != nil && rl.hardLimit() {
+		rl.footerSize = int64(len(rl.footer(FileInfo{
+			Filename: filename,
+			Time:     rl.clock.Now(),
+		})))
+	}
+}
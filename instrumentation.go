@@ -0,0 +1,48 @@
+package rotatelogs
+
+import (
+	"time"
+)
+
+// Instrumentation is notified about the operations performed by a
+// RotateLogs object, along with their timings and outcomes. It can be
+// used to feed metrics and tracing systems. See WithInstrumentation.
+//
+// The methods are called synchronously, so they should return quickly.
+// OnRotate is called while the RotateLogs object is locked, and must not
+// call any of its methods.
+type Instrumentation interface {
+	// OnWrite is called after each call to Write
+	OnWrite(WriteInfo)
+	// OnRotate is called after each attempt to start a new file,
+	// not counting the very first file
+	OnRotate(RotateInfo)
+	// OnPurge is called after old files have been removed
+	OnPurge(PurgeInfo)
+}
+
+// WriteInfo describes a call to Write
+type WriteInfo struct {
+	Start    time.Time
+	Duration time.Duration
+	Bytes    int   // number of bytes written
+	Err      error // error returned by Write, if any
+}
+
+// RotateInfo describes a rotation
+type RotateInfo struct {
+	Start        time.Time
+	Duration     time.Duration
+	PreviousFile string
+	CurrentFile  string // empty if the rotation failed
+	Err          error
+}
+
+// PurgeInfo describes the removal of old files
+type PurgeInfo struct {
+	Start    time.Time
+	Duration time.Duration
+	Files    []string // files that were to be removed
+	Failures int      // number of files that could not be removed
+	Err      error    // first error encountered, if any
+}
@@ -39,6 +39,7 @@ type RotateLogs struct {
 	globPattern   string
 	generation    int
 	linkName      string
+	vars          *patternVars // placeholders for the pattern and file names
 	maxAge        time.Duration
 	mutex         sync.RWMutex
 	eventHandler  Handler
@@ -48,8 +49,63 @@ type RotateLogs struct {
 	rotationSize  int64
 	rotationCount uint
 	forceNewFile  bool
+	strategy      rotationStrategy
+	stableFn      string
+	genFormatter  GenerationFormatter
+	fileMode      os.FileMode
+	dirMode       os.FileMode
+	owner         *fileOwner
+	watchFile     bool
+	watchInterval time.Duration
+	lastWatch     time.Time
+	lockFn        string
+	lockFh        *os.File
+	lockModTime   time.Time
+	lockSize      int64
+	lockTimeout   time.Duration
+	wg            sync.WaitGroup // background purges and event handlers
+	syncPolicy    SyncPolicy
+	syncStop      chan struct{}
+	dirty         bool // written to since the last sync
+	closed        bool
+	done          chan struct{} // closed when the object is closed
+	stats         statsCounters
+	instrument    Instrumentation
+	purger        *purger // shared purger, if any
+	mirrors       []*mirror
+	lineAtomic    bool
+	partial       []byte // incomplete line held back by WithLineAtomic
+	oversize      OversizePolicy
+	curSize       int64    // size of sizeFh, tracked by WithOversizePolicy
+	sizeFh        *os.File // file that curSize refers to
+	header        func(FileInfo) []byte
+	footer        func(FileInfo) []byte
+	headerSize    int64 // size of the header of the current file
+	footerSize    int64 // room reserved for the footer of the current file
 }
 
+// fileOwner holds the numeric uid and gid that new files are
+// changed to
+type fileOwner struct {
+	uid int
+	gid int
+}
+
+// rotationStrategy determines how the RotateLogs object moves from
+// one log file to the next
+type rotationStrategy int
+
+const (
+	// log directly into the file name generated from the pattern
+	strategyDirect rotationStrategy = iota
+	// log into a fixed file name, and rename it to the file name
+	// generated from the pattern upon rotation
+	strategyRename
+	// log into a fixed file name, and upon rotation copy it to the
+	// file name generated from the pattern, then truncate it in place
+	strategyCopyTruncate
+)
+
 // Clock is the interface used by the RotateLogs
 // object to determine the current time
 type Clock interface {
@@ -65,6 +121,18 @@ var UTC = clockFn(func() time.Time { return time.Now().UTC() })
 // returns the current time in the local timezone
 var Local = clockFn(time.Now)
 
+// GenerationFormatter is the interface used by the RotateLogs object
+// to generate file names when the file name generated from the pattern
+// clashes with an existing file. The generation is always greater
+// than zero.
+type GenerationFormatter interface {
+	FormatGeneration(filename string, generation int) string
+}
+
+// GenerationFormatFunc is a function satisfying the GenerationFormatter
+// interface
+type GenerationFormatFunc func(string, int) string
+
 // Option is used to pass optional arguments to
 // the RotateLogs constructor
 type Option interface {
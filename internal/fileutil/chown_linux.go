@@ -0,0 +1,23 @@
+//go:build linux
+// +build linux
+
+package fileutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ChownSupported reports whether Chown is supported on this platform
+const ChownSupported = true
+
+// Chown changes the numeric uid and gid of the given file.
+// A uid or gid of -1 means to not change that value
+func Chown(fh *os.File, uid, gid int) error {
+	if err := fh.Chown(uid, gid); err != nil {
+		return errors.Wrapf(err, "failed to change owner of %s", fh.Name())
+	}
+
+	return nil
+}
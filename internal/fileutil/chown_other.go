@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package fileutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ChownSupported reports whether Chown is supported on this platform
+const ChownSupported = false
+
+// Chown is only supported on Linux
+func Chown(fh *os.File, _, _ int) error {
+	return errors.Errorf("failed to change owner of %s: not supported on this platform", fh.Name())
+}
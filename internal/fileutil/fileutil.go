@@ -39,20 +39,35 @@ func GenerateFn(pattern *strftime.Strftime, clock interface{ Now() time.Time },
 	return pattern.FormatString(base)
 }
 
-// CreateFile creates a new file in the given path, creating parent directories
-// as necessary
-func CreateFile(filename string) (*os.File, error) {
+// CreateFile creates a new file in the given path with the given permission
+// bits, creating parent directories with dirPerm as necessary
+func CreateFile(filename string, perm, dirPerm os.FileMode) (*os.File, error) {
 	// make sure the dir is existed, eg:
 	// ./foo/bar/baz/hello.log must make sure ./foo/bar/baz is existed
 	dirname := filepath.Dir(filename)
-	if err := os.MkdirAll(dirname, 0755); err != nil {
+	if err := os.MkdirAll(dirname, dirPerm); err != nil {
 		return nil, errors.Wrapf(err, "failed to create directory %s", dirname)
 	}
 	// if we got here, then we need to create a file
-	fh, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	fh, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, perm)
 	if err != nil {
 		return nil, errors.Errorf("failed to open file %s: %s", filename, err)
 	}
 
 	return fh, nil
 }
+
+// MoveFile renames the file src to dst, creating parent directories
+// of dst with dirPerm as necessary
+func MoveFile(src, dst string, dirPerm os.FileMode) error {
+	dirname := filepath.Dir(dst)
+	if err := os.MkdirAll(dirname, dirPerm); err != nil {
+		return errors.Wrapf(err, "failed to create directory %s", dirname)
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		return errors.Wrapf(err, "failed to rename %s to %s", src, dst)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package fileutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// FlockSupported reports whether Flock is supported on this platform
+const FlockSupported = false
+
+// Flock is not supported on this platform
+func Flock(fh *os.File, _ bool) error {
+	return errors.Errorf("failed to lock %s: not supported on this platform", fh.Name())
+}
+
+// Funlock is not supported on this platform
+func Funlock(fh *os.File) error {
+	return errors.Errorf("failed to unlock %s: not supported on this platform", fh.Name())
+}
@@ -0,0 +1,43 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package fileutil
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// FlockSupported reports whether Flock is supported on this platform
+const FlockSupported = true
+
+// Flock acquires an advisory lock on the given file, blocking until
+// the lock is available. The lock is released by Funlock, or by the
+// kernel when the process exits
+func Flock(fh *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	for {
+		err := syscall.Flock(int(fh.Fd()), how)
+		if err == nil {
+			return nil
+		}
+		if err != syscall.EINTR {
+			return errors.Wrapf(err, "failed to lock %s", fh.Name())
+		}
+	}
+}
+
+// Funlock releases the advisory lock acquired by Flock
+func Funlock(fh *os.File) error {
+	if err := syscall.Flock(int(fh.Fd()), syscall.LOCK_UN); err != nil {
+		return errors.Wrapf(err, "failed to unlock %s", fh.Name())
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package fileutil
+
+// ProcessExists can't tell if a process exists on this platform,
+// so it always returns true
+func ProcessExists(_ int) bool {
+	return true
+}
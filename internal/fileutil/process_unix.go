@@ -0,0 +1,16 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package fileutil
+
+import (
+	"syscall"
+)
+
+// ProcessExists returns true if a process with the given pid exists
+func ProcessExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	// EPERM means the process exists, but belongs to somebody else
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package fileutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// SyncDir fsyncs the given directory, so that changes to its entries
+// (such as newly created files) are persisted
+func SyncDir(dirname string) error {
+	dh, err := os.Open(dirname)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open directory %s", dirname)
+	}
+	defer dh.Close()
+
+	if err := dh.Sync(); err != nil {
+		return errors.Wrapf(err, "failed to sync directory %s", dirname)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package fileutil
+
+// SyncDir is a no-op on windows, where directories can't be fsync'ed
+func SyncDir(_ string) error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package rotatelogs
+
+import (
+	"bytes"
+)
+
+// maxPartialLine is the number of bytes of an incomplete line that are
+// held back by WithLineAtomic. Longer lines are written out as they come
+const maxPartialLine = 1 << 20
+
+// writeLinesNolock writes the complete lines in p, along with any
+// incomplete line held back from previous writes, in a single write to
+// the file. The trailing incomplete line of p, if any, is held back
+// until it is completed by a later write
+//
+// must be locked during this operation
+func (rl *RotateLogs) writeLinesNolock(p []byte) (int, error) {
+	var lines, rest []byte
+	if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+		lines, rest = p[:i+1], p[i+1:]
+	} else if len(rl.partial)+len(p) > maxPartialLine {
+		lines = p
+	} else {
+		rl.partial = append(rl.partial, p...)
+		return len(p), nil
+	}
+
+	pending := len(rl.partial)
+	buf := append(rl.partial, lines...)
+	n, err := rl.writeNolock(buf)
+	if err != nil {
+		rl.partial = rl.partial[:0]
+		n -= pending
+		if n < 0 {
+			n = 0
+		}
+		return n, err
+	}
+
+	rl.partial = append(rl.partial[:0], rest...)
+	return len(p), nil
+}
+
+// flushPartialNolock writes out the incomplete line held back by
+// WithLineAtomic, if any
+//
+// must be locked during this operation
+func (rl *RotateLogs) flushPartialNolock() error {
+	if len(rl.partial) == 0 {
+		return nil
+	}
+
+	_, err := rl.writeNolock(rl.partial)
+	rl.partial = nil
+	return err
+}
@@ -0,0 +1,145 @@
+package rotatelogs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/file-rotatelogs/internal/fileutil"
+)
+
+// createLockFile exclusively creates the lock file that guards the
+// symlink and purge steps of a rotation, and records the pid of the
+// current process and the current time in it.
+//
+// If the lock file already exists but is stale, that is, its owner is
+// no longer alive or it is older than rl.lockTimeout, the lock is
+// broken and creation is retried once. If the lock file is held by
+// somebody else, an error matching ErrRotateLocked is returned.
+func (rl *RotateLogs) createLockFile(lockfn string) (*os.File, error) {
+	fh, err := os.OpenFile(lockfn, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		stale, ok := rl.isStaleLockFile(lockfn)
+		if !ok {
+			return nil, withSentinel(ErrRotateLocked, err)
+		}
+
+		if !breakLockFile(lockfn, stale) {
+			// somebody else broke the lock before us
+			return nil, withSentinel(ErrRotateLocked, err)
+		}
+
+		fh, err = os.OpenFile(lockfn, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			if os.IsExist(err) {
+				// somebody else broke the lock before us
+				return nil, withSentinel(ErrRotateLocked, err)
+			}
+
+			return nil, err
+		}
+	}
+
+	// This is purely informational, so errors are ignored. Lock files
+	// without this information are judged by their modification time
+	fmt.Fprintf(fh, "%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339Nano))
+
+	return fh, nil
+}
+
+// staleLockFile describes a lock file that was judged stale
+type staleLockFile struct {
+	info    os.FileInfo
+	content []byte
+}
+
+// sameAs reports whether fi and content describe the same lock file.
+// File identity alone is not enough, as the file system may reuse it
+// for a lock file created after the stale one was removed
+func (s *staleLockFile) sameAs(fi os.FileInfo, content []byte) bool {
+	return os.SameFile(s.info, fi) && s.info.ModTime().Equal(fi.ModTime()) && bytes.Equal(s.content, content)
+}
+
+// breakLockFile removes the lock file that was found to be stale.
+//
+// Between the time the lock file was judged stale and now, another
+// process may have broken the lock and created a new one, which must be
+// left alone. So the lock file is first moved out of the way under a
+// unique name, and only removed if it is the very file that was judged
+// stale. Otherwise it is put back, and false is returned.
+func breakLockFile(lockfn string, stale *staleLockFile) bool {
+	// The "_lock" suffix keeps the file from being purged
+	tmpfn := fmt.Sprintf("%s.%d.%d_lock", lockfn, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockfn, tmpfn); err != nil {
+		return false
+	}
+	defer os.Remove(tmpfn)
+
+	fi, err := os.Stat(tmpfn)
+	if err == nil {
+		content, err := ioutil.ReadFile(tmpfn)
+		if err == nil && stale.sameAs(fi, content) {
+			return true
+		}
+	}
+
+	// This is a live lock. Put it back, unless yet another lock file
+	// has been created in the meantime
+	os.Link(tmpfn, lockfn)
+	return false
+}
+
+// isStaleLockFile reports whether the owner of the given lock file is
+// no longer alive, or whether the lock file is older than rl.lockTimeout.
+// If so, it also returns what the stale file looked like
+func (rl *RotateLogs) isStaleLockFile(lockfn string) (*staleLockFile, bool) {
+	fh, err := os.Open(lockfn)
+	if err != nil {
+		return nil, false
+	}
+	defer fh.Close()
+
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, false
+	}
+
+	content, err := ioutil.ReadAll(fh)
+	if err != nil {
+		return nil, false
+	}
+
+	pid := -1
+	createdAt := fi.ModTime()
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	if scanner.Scan() {
+		if v, err := strconv.Atoi(strings.TrimSpace(scanner.Text())); err == nil {
+			pid = v
+		}
+	}
+	if scanner.Scan() {
+		if v, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(scanner.Text())); err == nil {
+			createdAt = v
+		}
+	}
+
+	stale := &staleLockFile{info: fi, content: content}
+
+	// Lock files created by this process may belong to another
+	// RotateLogs object, so they are only judged by their age
+	if pid > 0 && pid != os.Getpid() && !fileutil.ProcessExists(pid) {
+		return stale, true
+	}
+
+	return stale, rl.lockTimeout > 0 && time.Since(createdAt) > rl.lockTimeout
+}
@@ -0,0 +1,329 @@
+package rotatelogs
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Manager maintains one RotateLogs object per stream, such as a tenant
+// or a component, identified by a key. Objects are created lazily from
+// a pattern containing the "%{key}" placeholder, the first time their
+// stream is written to:
+//
+//	m, err := rotatelogs.NewManager("/var/log/tenants/%{key}/access.%Y%m%d")
+//	w, err := m.Writer("acme") // writes to /var/log/tenants/acme/access.20240501
+//
+// Old files of all streams are removed by a single background goroutine.
+// Streams that have not been written to for a while may be closed with
+// WithIdleTimeout, and the number of streams that are open at the same
+// time may be limited with WithMaxOpenStreams. Closed streams are
+// transparently opened again when they are written to.
+type Manager struct {
+	mutex       sync.Mutex
+	pattern     string
+	options     []Option
+	idleTimeout time.Duration
+	maxOpen     int
+	streams     map[string]*list.Element
+	lru         *list.List // of *managedStream, most recently used first
+	purger      *purger
+	closing     sync.WaitGroup // streams being closed in the background
+	closed      bool
+	janitorStop chan struct{}
+	janitorDone chan struct{}
+}
+
+type managedStream struct {
+	key      string
+	rl       *RotateLogs
+	lastUsed time.Time
+}
+
+// NewManager creates a Manager. The pattern must contain the "%{key}"
+// placeholder, which is defined for each stream using WithPatternVar.
+// The placeholder may thus also be used in the file names given to
+// WithLinkName, WithRenameOnRotate, WithCopyTruncate and
+// WithProcessLock, which would otherwise be shared by all streams.
+//
+// Besides the options accepted by New, which are applied to every
+// stream, NewManager accepts WithIdleTimeout and WithMaxOpenStreams.
+func NewManager(pattern string, options ...Option) (*Manager, error) {
+	if !strings.Contains(pattern, "%{key}") {
+		return nil, withSentinel(ErrInvalidPattern, errors.Errorf(`pattern %#v must contain %%{key}`, pattern))
+	}
+
+	var idleTimeout time.Duration
+	var maxOpen int
+	var rlOptions []Option
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyIdleTimeout:
+			idleTimeout = o.Value().(time.Duration)
+		case optkeyMaxOpenStreams:
+			maxOpen = o.Value().(int)
+		default:
+			rlOptions = append(rlOptions, o)
+		}
+	}
+
+	m := &Manager{
+		pattern:     pattern,
+		options:     rlOptions,
+		idleTimeout: idleTimeout,
+		maxOpen:     maxOpen,
+		streams:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+
+	// Catch invalid options early, instead of on the first write
+	rl, err := m.newStream("key")
+	if err != nil {
+		return nil, err
+	}
+	rl.Close()
+
+	m.purger = newPurger()
+
+	if idleTimeout > 0 {
+		m.janitorStop = make(chan struct{})
+		m.janitorDone = make(chan struct{})
+		go m.runJanitor(idleTimeout)
+	}
+
+	return m, nil
+}
+
+// newStream creates the RotateLogs object for key
+func (m *Manager) newStream(key string) (*RotateLogs, error) {
+	options := append([]Option{}, m.options...)
+	options = append(options, WithPatternVar("key", func() string { return key }))
+	return New(m.pattern, options...)
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return errors.New("stream key must not be empty")
+	}
+	if key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return errors.Errorf("invalid stream key %#v", key)
+	}
+	return nil
+}
+
+// Writer returns an io.Writer that writes to the stream identified by
+// key. The returned writer stays valid even if the stream is closed
+// because it was idle or because of the open stream limit, in which case
+// the stream is opened again on the next write.
+func (m *Manager) Writer(key string) (io.Writer, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	return &managedWriter{manager: m, key: key}, nil
+}
+
+type managedWriter struct {
+	manager *Manager
+	key     string
+}
+
+func (w *managedWriter) Write(p []byte) (int, error) {
+	return w.manager.Write(w.key, p)
+}
+
+// maxWriteAttempts is the number of times Manager.Write looks up a
+// stream that keeps getting closed before it gives up
+const maxWriteAttempts = 3
+
+// Write writes p to the stream identified by key
+func (m *Manager) Write(key string, p []byte) (int, error) {
+	var err error
+	for i := 0; i < maxWriteAttempts; i++ {
+		var rl *RotateLogs
+		rl, err = m.stream(key)
+		if err != nil {
+			return 0, err
+		}
+
+		var n int
+		n, err = rl.Write(p)
+		if !errors.Is(err, ErrClosed) {
+			return n, err
+		}
+		// The stream was closed between looking it up and writing
+		// to it. Unless the manager itself was closed, try again
+	}
+	return 0, errors.Wrapf(err, `stream %#v was closed %d times while writing to it`, key, maxWriteAttempts)
+}
+
+// stream returns the RotateLogs object for key, creating it if necessary
+func (m *Manager) stream(key string) (*RotateLogs, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	rl, evicted, err := m.streamNolock(key)
+	if len(evicted) > 0 {
+		// Closing a stream waits for its pending writes, so it is done
+		// in the background, lest a slow stream holds up the others
+		m.closing.Add(1)
+		go func() {
+			defer m.closing.Done()
+			if err := closeStreams(evicted); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+		}()
+	}
+
+	return rl, err
+}
+
+// streamNolock returns the RotateLogs object for key, creating it if
+// necessary, along with the streams that were removed to make room for
+// it. The caller is responsible for closing the latter
+//
+// must be locked during this operation
+func (m *Manager) streamNolock(key string) (*RotateLogs, []*RotateLogs, error) {
+	if m.closed {
+		return nil, nil, ErrClosed
+	}
+
+	if e, ok := m.streams[key]; ok {
+		s := e.Value.(*managedStream)
+		s.lastUsed = time.Now()
+		m.lru.MoveToFront(e)
+		return s.rl, nil, nil
+	}
+
+	rl, err := m.newStream(key)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, `failed to create stream %#v`, key)
+	}
+	rl.purger = m.purger
+
+	// Make room for the new stream by removing the least recently used ones
+	var evicted []*RotateLogs
+	for m.maxOpen > 0 && m.lru.Len() >= m.maxOpen {
+		evicted = append(evicted, m.removeStreamNolock(m.lru.Back()))
+	}
+
+	m.streams[key] = m.lru.PushFront(&managedStream{
+		key:      key,
+		rl:       rl,
+		lastUsed: time.Now(),
+	})
+
+	return rl, evicted, nil
+}
+
+// removeStreamNolock removes a stream from the manager, and returns its
+// RotateLogs object, which the caller is responsible for closing
+//
+// must be locked during this operation
+func (m *Manager) removeStreamNolock(e *list.Element) *RotateLogs {
+	s := m.lru.Remove(e).(*managedStream)
+	delete(m.streams, s.key)
+	return s.rl
+}
+
+// closeStreams closes the given streams, and returns their errors
+func closeStreams(streams []*RotateLogs) error {
+	var errs multiError
+	for _, rl := range streams {
+		if err := rl.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Streams returns the keys of the streams that are currently open,
+// most recently used first
+func (m *Manager) Streams() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]string, 0, m.lru.Len())
+	for e := m.lru.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*managedStream).key)
+	}
+	return keys
+}
+
+func (m *Manager) runJanitor(idleTimeout time.Duration) {
+	defer close(m.janitorDone)
+
+	interval := idleTimeout / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.janitorStop:
+			return
+		case <-ticker.C:
+			m.closeIdle(idleTimeout)
+		}
+	}
+}
+
+// closeIdle closes the streams that have not been used for idleTimeout
+func (m *Manager) closeIdle(idleTimeout time.Duration) {
+	m.mutex.Lock()
+	var idle []*RotateLogs
+	cutoff := time.Now().Add(-idleTimeout)
+	for e := m.lru.Back(); e != nil; e = m.lru.Back() {
+		if e.Value.(*managedStream).lastUsed.After(cutoff) {
+			// the remaining streams have been used more recently
+			break
+		}
+		idle = append(idle, m.removeStreamNolock(e))
+	}
+	m.mutex.Unlock()
+
+	closeStreams(idle)
+}
+
+// Close closes all streams, and waits for pending purges to finish.
+// The manager cannot be used anymore afterwards.
+func (m *Manager) Close() error {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return nil
+	}
+	m.closed = true
+
+	var streams []*RotateLogs
+	for e := m.lru.Back(); e != nil; e = m.lru.Back() {
+		streams = append(streams, m.removeStreamNolock(e))
+	}
+	m.mutex.Unlock()
+
+	err := closeStreams(streams)
+	m.closing.Wait()
+
+	if m.janitorStop != nil {
+		close(m.janitorStop)
+		<-m.janitorDone
+	}
+	m.purger.stop()
+
+	return err
+}
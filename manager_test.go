@@ -0,0 +1,189 @@
+package rotatelogs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-manager")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("InvalidPattern", func(t *testing.T) {
+		_, err := rotatelogs.NewManager(filepath.Join(dir, "log"))
+		assert.True(t, errors.Is(err, rotatelogs.ErrInvalidPattern), "error should match ErrInvalidPattern (got %v)", err)
+
+		_, err = rotatelogs.NewManager(filepath.Join(dir, "%{key}.log"), rotatelogs.WithMaxAge(time.Hour), rotatelogs.WithRotationCount(1))
+		assert.True(t, errors.Is(err, rotatelogs.ErrConflictingOptions), "options should be validated (got %v)", err)
+	})
+
+	t.Run("Streams", func(t *testing.T) {
+		m, err := rotatelogs.NewManager(
+			filepath.Join(dir, "streams", "%{key}", "access.log"),
+			rotatelogs.WithLinkName(filepath.Join(dir, "streams", "%{key}.current")),
+		)
+		if !assert.NoError(t, err, "rotatelogs.NewManager should succeed") {
+			return
+		}
+		defer m.Close()
+
+		for _, key := range []string{"acme", "100%"} {
+			w, err := m.Writer(key)
+			if !assert.NoError(t, err, "m.Writer should succeed") {
+				return
+			}
+			w.Write([]byte(key))
+
+			content, err := ioutil.ReadFile(filepath.Join(dir, "streams", key, "access.log"))
+			if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+				return
+			}
+			assert.Equal(t, key, string(content), "stream should be written to its own file")
+
+			_, err = os.Readlink(filepath.Join(dir, "streams", key+".current"))
+			assert.NoError(t, err, "each stream should have its own link")
+		}
+
+		for _, key := range []string{"", "..", "a/b"} {
+			_, err := m.Writer(key)
+			assert.Error(t, err, "m.Writer should fail for key %#v", key)
+		}
+	})
+
+	t.Run("MaxOpenStreams", func(t *testing.T) {
+		m, err := rotatelogs.NewManager(filepath.Join(dir, "limit.%{key}.log"), rotatelogs.WithMaxOpenStreams(2))
+		if !assert.NoError(t, err, "rotatelogs.NewManager should succeed") {
+			return
+		}
+		defer m.Close()
+
+		a, _ := m.Writer("a")
+		b, _ := m.Writer("b")
+		c, _ := m.Writer("c")
+		a.Write([]byte("1"))
+		b.Write([]byte("1"))
+		c.Write([]byte("1"))
+		assert.Equal(t, []string{"c", "b"}, m.Streams(), "least recently used stream should be closed")
+
+		_, err = a.Write([]byte("2"))
+		assert.NoError(t, err, "writing to a closed stream should reopen it")
+		assert.Equal(t, []string{"a", "c"}, m.Streams(), "streams should match")
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, "limit.a.log"))
+		if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			return
+		}
+		assert.Equal(t, "12", string(content), "reopened stream should append to its file")
+	})
+
+	t.Run("SlowStream", func(t *testing.T) {
+		// The handler blocks the first write to the slow stream while
+		// it holds the lock of its RotateLogs object
+		release := make(chan struct{})
+		handler := rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+			if strings.HasSuffix(e.(*rotatelogs.FileRotatedEvent).CurrentFile(), "slow.log") {
+				<-release
+			}
+		})
+
+		m, err := rotatelogs.NewManager(
+			filepath.Join(dir, "slow.%{key}.log"),
+			rotatelogs.WithMaxOpenStreams(1),
+			rotatelogs.WithHandler(handler),
+		)
+		if !assert.NoError(t, err, "rotatelogs.NewManager should succeed") {
+			return
+		}
+		defer m.Close()
+
+		slowDone := make(chan struct{})
+		go func() {
+			defer close(slowDone)
+			m.Write("slow", []byte("1"))
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		// Evicting the slow stream should not block other streams
+		fastDone := make(chan struct{})
+		go func() {
+			defer close(fastDone)
+			m.Write("fast", []byte("1"))
+			m.Write("other", []byte("1"))
+		}()
+		select {
+		case <-fastDone:
+		case <-time.After(time.Second):
+			t.Errorf("writing to other streams should not wait for the slow stream")
+		}
+
+		close(release)
+		<-slowDone
+		<-fastDone
+	})
+
+	t.Run("IdleTimeout", func(t *testing.T) {
+		m, err := rotatelogs.NewManager(filepath.Join(dir, "idle.%{key}.log"), rotatelogs.WithIdleTimeout(50*time.Millisecond))
+		if !assert.NoError(t, err, "rotatelogs.NewManager should succeed") {
+			return
+		}
+		defer m.Close()
+
+		m.Write("a", []byte("Hello, World!"))
+		assert.Equal(t, []string{"a"}, m.Streams(), "stream should be open")
+
+		time.Sleep(200 * time.Millisecond)
+		assert.Empty(t, m.Streams(), "idle stream should be closed")
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		m, err := rotatelogs.NewManager(
+			filepath.Join(dir, "purge", "%{key}.log"),
+			rotatelogs.WithRotationSize(1),
+			rotatelogs.WithMaxAge(-1),
+			rotatelogs.WithRotationCount(2),
+		)
+		if !assert.NoError(t, err, "rotatelogs.NewManager should succeed") {
+			return
+		}
+
+		for i := 0; i < 5; i++ {
+			m.Write("a", []byte("Hello, World!"))
+			m.Write("b", []byte("Hello, World!"))
+		}
+		if !assert.NoError(t, m.Close(), "m.Close should succeed") {
+			return
+		}
+
+		for _, key := range []string{"a", "b"} {
+			matches, err := filepath.Glob(filepath.Join(dir, "purge", key+".log*"))
+			if !assert.NoError(t, err, "filepath.Glob should succeed") {
+				return
+			}
+			assert.Len(t, matches, 2, "old files of each stream should be purged (got %v)", matches)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		m, err := rotatelogs.NewManager(filepath.Join(dir, "closed.%{key}.log"))
+		if !assert.NoError(t, err, "rotatelogs.NewManager should succeed") {
+			return
+		}
+		w, _ := m.Writer("a")
+		w.Write([]byte("Hello, World!"))
+
+		assert.NoError(t, m.Close(), "m.Close should succeed")
+		_, err = w.Write([]byte("Hello, World!"))
+		assert.True(t, errors.Is(err, rotatelogs.ErrClosed), "error should match ErrClosed (got %v)", err)
+	})
+}
@@ -0,0 +1,67 @@
+package rotatelogs
+
+import (
+	"io"
+)
+
+// mirrorQueueSize is the number of writes that may be waiting to be
+// copied to each mirror before further writes are dropped
+const mirrorQueueSize = 1024
+
+// mirror copies writes to another io.Writer on a background goroutine,
+// so that a slow or failing mirror never holds up the log file
+type mirror struct {
+	w     io.Writer
+	queue chan []byte
+}
+
+func (rl *RotateLogs) startMirror(w io.Writer) {
+	m := &mirror{
+		w:     w,
+		queue: make(chan []byte, mirrorQueueSize),
+	}
+	rl.mirrors = append(rl.mirrors, m)
+
+	rl.wg.Add(1)
+	go func() {
+		defer rl.wg.Done()
+		for p := range m.queue {
+			if _, err := m.w.Write(p); err != nil {
+				rl.stats.recordMirrorError()
+			}
+		}
+	}()
+}
+
+// mirrorNolock queues p to be written to each mirror. If the queue of a
+// mirror is full, p is dropped for that mirror
+//
+// must be locked during this operation
+func (rl *RotateLogs) mirrorNolock(p []byte) {
+	if len(rl.mirrors) == 0 || len(p) == 0 {
+		return
+	}
+
+	// the caller may reuse p as soon as Write returns
+	buf := make([]byte, len(p))
+	copy(buf, p)
+
+	for _, m := range rl.mirrors {
+		select {
+		case m.queue <- buf:
+		default:
+			rl.stats.recordMirrorDrop()
+		}
+	}
+}
+
+// stopMirrorsNolock lets the mirrors finish the writes that have been
+// queued so far, and stop
+//
+// must be locked during this operation
+func (rl *RotateLogs) stopMirrorsNolock() {
+	for _, m := range rl.mirrors {
+		close(m.queue)
+	}
+	rl.mirrors = nil
+}
@@ -1,20 +1,41 @@
 package rotatelogs
 
 import (
+	"io"
+	"os"
 	"time"
 
 	"github.com/lestrrat-go/file-rotatelogs/internal/option"
 )
 
 const (
-	optkeyClock         = "clock"
-	optkeyHandler       = "handler"
-	optkeyLinkName      = "link-name"
-	optkeyMaxAge        = "max-age"
-	optkeyRotationTime  = "rotation-time"
-	optkeyRotationSize  = "rotation-size"
-	optkeyRotationCount = "rotation-count"
-	optkeyForceNewFile  = "force-new-file"
+	optkeyClock            = "clock"
+	optkeyHandler          = "handler"
+	optkeyLinkName         = "link-name"
+	optkeyMaxAge           = "max-age"
+	optkeyRotationTime     = "rotation-time"
+	optkeyRotationSize     = "rotation-size"
+	optkeyRotationCount    = "rotation-count"
+	optkeyForceNewFile     = "force-new-file"
+	optkeyRenameOnRotate   = "rename-on-rotate"
+	optkeyGenerationFormat = "generation-format"
+	optkeyFileMode         = "file-mode"
+	optkeyDirMode          = "dir-mode"
+	optkeyOwner            = "owner"
+	optkeyCopyTruncate     = "copy-truncate"
+	optkeyFileWatch        = "file-watch"
+	optkeyProcessLock      = "process-lock"
+	optkeyStaleLockTimeout = "stale-lock-timeout"
+	optkeySyncPolicy       = "sync-policy"
+	optkeyInstrumentation  = "instrumentation"
+	optkeyIdleTimeout      = "idle-timeout"
+	optkeyMaxOpenStreams   = "max-open-streams"
+	optkeyPatternVar       = "pattern-var"
+	optkeyMirror           = "mirror"
+	optkeyLineAtomic       = "line-atomic"
+	optkeyOversizePolicy   = "oversize-policy"
+	optkeyFileHeader       = "file-header"
+	optkeyFileFooter       = "file-footer"
 )
 
 // WithClock creates a new Option that sets a clock
@@ -50,11 +71,20 @@ func WithLinkName(s string) Option {
 
 // WithMaxAge creates a new Option that sets the
 // max age of a log file before it gets purged from
-// the file system.
+// the file system. A negative value disables purging by age,
+// which is only effective together with WithRotationCount:
+// otherwise the default max age of 7 days applies.
 func WithMaxAge(d time.Duration) Option {
 	return option.New(optkeyMaxAge, d)
 }
 
+// WithMaxAgeString is like WithMaxAge, but takes a human readable
+// duration such as "14d" (see ParseRetention). If the string cannot be
+// parsed, New returns an error.
+func WithMaxAgeString(s string) Option {
+	return option.New(optkeyMaxAge, s)
+}
+
 // WithRotationTime creates a new Option that sets the
 // time between rotation.
 func WithRotationTime(d time.Duration) Option {
@@ -67,9 +97,17 @@ func WithRotationSize(s int64) Option {
 	return option.New(optkeyRotationSize, s)
 }
 
+// WithRotationSizeString is like WithRotationSize, but takes a human
+// readable size such as "512MiB" (see ParseSize). If the string cannot
+// be parsed, New returns an error.
+func WithRotationSizeString(s string) Option {
+	return option.New(optkeyRotationSize, s)
+}
+
 // WithRotationCount creates a new Option that sets the
 // number of files should be kept before it gets
-// purged from the file system.
+// purged from the file system. The count includes the current
+// file, and the most recently modified files are kept.
 func WithRotationCount(n uint) Option {
 	return option.New(optkeyRotationCount, n)
 }
@@ -87,3 +125,218 @@ func WithHandler(h Handler) Option {
 func ForceNewFile() Option {
 	return option.New(optkeyForceNewFile, true)
 }
+
+// WithRenameOnRotate creates a new Option that makes the RotateLogs
+// object always write to the given file name, instead of the file
+// name generated from the pattern. Upon rotation, the file is renamed
+// to the file name generated from the pattern for the period that
+// the file was written in (e.g. "app.log" -> "app.2024-05-01T00.log"),
+// and a new file is created in its place.
+//
+// This is useful for log shippers that track files by path and inode.
+func WithRenameOnRotate(filename string) Option {
+	return option.New(optkeyRenameOnRotate, filename)
+}
+
+// WithGenerationFormat creates a new Option that specifies how file
+// names are generated when the file name generated from the pattern
+// clashes with an existing file.
+//
+// By default rotatelogs.GenerationSuffix, which appends a numeric
+// suffix such as "foo.log.1", is used. See also GenerationBeforeExt
+// and GenerationZeroPadded.
+func WithGenerationFormat(f GenerationFormatter) Option {
+	return option.New(optkeyGenerationFormat, f)
+}
+
+// WithFileMode creates a new Option that sets the permission bits
+// used when creating log files. The default is 0644. As with
+// os.OpenFile, the process umask is applied.
+func WithFileMode(mode os.FileMode) Option {
+	return option.New(optkeyFileMode, mode)
+}
+
+// WithDirMode creates a new Option that sets the permission bits
+// used when creating parent directories of log files. The default is
+// 0755. As with os.MkdirAll, the process umask is applied.
+func WithDirMode(mode os.FileMode) Option {
+	return option.New(optkeyDirMode, mode)
+}
+
+// WithOwner creates a new Option that changes the numeric uid and gid
+// of log files after they have been created. A uid or gid of -1 means
+// to not change that value.
+//
+// This option is only supported on Linux. On other platforms New
+// returns ErrUnsupported.
+func WithOwner(uid, gid int) Option {
+	return option.New(optkeyOwner, &fileOwner{uid: uid, gid: gid})
+}
+
+// WithCopyTruncate creates a new Option that makes the RotateLogs
+// object always write to the given file name, instead of the file
+// name generated from the pattern. Upon rotation, the contents of the
+// file are copied to the file name generated from the pattern for the
+// period that the file was written in, and the file is then truncated
+// in place.
+//
+// This is the equivalent of logrotate's copytruncate: other processes
+// holding the file open keep working. Note that data written by other
+// processes between the copy and the truncation is lost.
+//
+// This option cannot be used together with WithRenameOnRotate.
+func WithCopyTruncate(filename string) Option {
+	return option.New(optkeyCopyTruncate, filename)
+}
+
+// WithFileWatch creates a new Option that makes the RotateLogs object
+// check if the file it is writing to has been removed or renamed by
+// somebody else (e.g. an operator, or an external log rotation tool),
+// and if so, reopen the file under its original name.
+//
+// The check is performed before writing, at most once every interval.
+// If the interval is 0, the check is performed on every write.
+func WithFileWatch(interval time.Duration) Option {
+	return option.New(optkeyFileWatch, interval)
+}
+
+// WithProcessLock creates a new Option that coordinates rotation
+// among multiple processes writing to the same pattern (e.g. workers
+// of a prefork server), using an advisory lock (flock) on the given
+// file.
+//
+// Only one process rotates the logs at a time, and the file name it
+// switched to is recorded in the lock file. Other processes switch
+// over to that file instead of rotating on their own. Since advisory
+// locks are released when the process exits, a crashed process does
+// not leave a stale lock behind.
+//
+// All processes must use the same lock file, pattern, and options.
+// This option is only supported on Unix-like platforms (New fails with
+// ErrUnsupported elsewhere), and cannot be used with WithRenameOnRotate or
+// WithCopyTruncate.
+func WithProcessLock(filename string) Option {
+	return option.New(optkeyProcessLock, filename)
+}
+
+// WithStaleLockTimeout creates a new Option that sets the age after
+// which a lock file (the "<filename>_lock" file created during
+// rotation) is considered stale, and gets removed.
+//
+// Lock files record the pid of the process that created them, and
+// on platforms where it can be detected, lock files whose owner is no
+// longer alive are removed regardless of their age. The default is
+// one minute. A value of 0 or less disables age based detection.
+func WithStaleLockTimeout(d time.Duration) Option {
+	return option.New(optkeyStaleLockTimeout, d)
+}
+
+// WithSyncPolicy creates a new Option that determines when the file
+// being written to is fsync'ed. See SyncPolicy for details.
+//
+// By default rotatelogs.SyncNever is used.
+func WithSyncPolicy(p SyncPolicy) Option {
+	return option.New(optkeySyncPolicy, p)
+}
+
+// WithInstrumentation sets an Instrumentation that is notified about
+// writes, rotations and purges, along with their timings and outcomes.
+func WithInstrumentation(i Instrumentation) Option {
+	return option.New(optkeyInstrumentation, i)
+}
+
+// WithIdleTimeout creates a new Option that makes a Manager close
+// streams that have not been written to for the given duration.
+// It is only accepted by NewManager.
+func WithIdleTimeout(d time.Duration) Option {
+	return option.New(optkeyIdleTimeout, d)
+}
+
+// WithMaxOpenStreams creates a new Option that limits the number of
+// streams a Manager keeps open at the same time. When the limit is
+// reached, the least recently used stream is closed.
+// It is only accepted by NewManager.
+func WithMaxOpenStreams(n int) Option {
+	return option.New(optkeyMaxOpenStreams, n)
+}
+
+// WithPatternVar creates a new Option that defines the placeholder
+// %{name}, which may then be used in the pattern given to New, as well
+// as in the file names given to WithLinkName, WithRenameOnRotate,
+// WithCopyTruncate and WithProcessLock. The placeholders %{hostname} and
+// %{pid} are always defined, but may be overridden.
+//
+// fn is called once, when the RotateLogs object is created, and its
+// result is used verbatim: it is not interpreted as strftime verbs.
+func WithPatternVar(name string, fn func() string) Option {
+	return option.New(optkeyPatternVar, &patternVar{name: name, fn: fn})
+}
+
+// WithMirror creates a new Option that copies everything written to the
+// log files to w as well, for example os.Stdout. The option may be given
+// several times to add several mirrors.
+//
+// Mirrors are written to on a background goroutine, so they never block
+// or fail writes to the log files: their errors are only counted in
+// Stats, and if a mirror falls too far behind, writes are dropped for
+// that mirror.
+func WithMirror(w io.Writer) Option {
+	return option.New(optkeyMirror, w)
+}
+
+// WithLineAtomic creates a new Option that makes sure that lines are
+// never split across files or across writes to the file. An incomplete
+// line at the end of a Write is held back until a later Write completes
+// it, and each write to the file only contains complete lines, so that
+// lines written by several processes to the same file do not interleave.
+//
+// Incomplete lines longer than 1MiB are written out as they come. An
+// incomplete line that is still held back when the object is closed is
+// written out as is.
+func WithLineAtomic() Option {
+	return option.New(optkeyLineAtomic, true)
+}
+
+// WithOversizePolicy creates a new Option that turns the rotation size
+// into a hard limit: the file is rotated before a write that would make
+// it exceed the rotation size, rather than once it has reached it, and the
+// size of the file is tracked in memory so that the limit is enforced
+// exactly. Writes larger than the rotation size are handled according to
+// p: they are either written to a file of their own with OversizeAllow, or
+// split on line boundaries with OversizeSplitLines. Combined with
+// WithLineAtomic, the rotation size is checked against complete lines.
+//
+// Headers and footers (see WithFileHeader and WithFileFooter) count
+// toward the rotation size: the header is part of the file when the
+// space left in it is computed, and room is reserved for the footer.
+//
+// When used with WithProcessLock, the size is checked against the file
+// system instead, since other processes write to the file as well.
+func WithOversizePolicy(p OversizePolicy) Option {
+	return option.New(optkeyOversizePolicy, p)
+}
+
+// WithFileHeader creates a new Option that writes the data returned by
+// fn at the beginning of each new file, for example a CSV header or a
+// "#Fields:" line. It is not written when appending to an existing,
+// non-empty file.
+//
+// If the header cannot be written, Rotate returns an error, although
+// the file has been rotated. Failures during Write are only reported
+// on stderr, as with other rotation errors.
+func WithFileHeader(fn func(FileInfo) []byte) Option {
+	return option.New(optkeyFileHeader, fn)
+}
+
+// WithFileFooter creates a new Option that writes the data returned by
+// fn at the end of each file, right before the RotateLogs object moves
+// on to the next file. It is not written when the object is closed,
+// since the file may be appended to later. Errors are handled as for
+// WithFileHeader.
+//
+// When the rotation size is a hard limit (see WithOversizePolicy), fn is also called when a file is started, with
+// an empty NextFile, to find out how much room to reserve for the
+// footer. The footer should thus not grow with the length of NextFile.
+func WithFileFooter(fn func(FileInfo) []byte) Option {
+	return option.New(optkeyFileFooter, fn)
+}
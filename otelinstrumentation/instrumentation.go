@@ -0,0 +1,158 @@
+// Package otelinstrumentation provides a rotatelogs.Instrumentation that
+// reports writes, rotations and purges as OpenTelemetry metrics and
+// spans.
+//
+//	instr, err := otelinstrumentation.New()
+//	if err != nil {
+//		return err
+//	}
+//	rl, err := rotatelogs.New(pattern, rotatelogs.WithInstrumentation(instr))
+package otelinstrumentation
+
+import (
+	"context"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const scopeName = "github.com/lestrrat-go/file-rotatelogs"
+
+// Instrumentation implements rotatelogs.Instrumentation
+type Instrumentation struct {
+	tracer             trace.Tracer
+	attrs              []attribute.KeyValue
+	slowWriteThreshold time.Duration
+
+	writeDuration    metric.Float64Histogram
+	writtenBytes     metric.Int64Counter
+	rotationDuration metric.Float64Histogram
+	purgeDuration    metric.Float64Histogram
+	purgedFiles      metric.Int64Counter
+	purgeFailures    metric.Int64Counter
+}
+
+var _ rotatelogs.Instrumentation = (*Instrumentation)(nil)
+
+// New creates an Instrumentation. The following metrics are created:
+//
+//	rotatelogs.write.duration     histogram of Write durations, in seconds
+//	rotatelogs.written            number of bytes written
+//	rotatelogs.rotation.duration  histogram of rotation durations, in seconds
+//	rotatelogs.purge.duration     histogram of purge durations, in seconds
+//	rotatelogs.purged             number of files removed
+//	rotatelogs.purge.failures     number of files that could not be removed
+//
+// Durations carry an "error" attribute that tells whether the operation
+// failed. Each rotation and purge also produces a span, as do writes
+// slower than the threshold given by WithSlowWriteThreshold.
+func New(options ...Option) (*Instrumentation, error) {
+	mp := otel.GetMeterProvider()
+	tp := otel.GetTracerProvider()
+	var attrs []attribute.KeyValue
+	var slowWriteThreshold time.Duration
+
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyMeterProvider:
+			mp = o.Value().(metric.MeterProvider)
+		case optkeyTracerProvider:
+			tp = o.Value().(trace.TracerProvider)
+		case optkeyAttributes:
+			attrs = o.Value().([]attribute.KeyValue)
+		case optkeySlowWriteThreshold:
+			slowWriteThreshold = o.Value().(time.Duration)
+		}
+	}
+
+	meter := mp.Meter(scopeName)
+	i := &Instrumentation{
+		tracer:             tp.Tracer(scopeName),
+		attrs:              attrs,
+		slowWriteThreshold: slowWriteThreshold,
+	}
+
+	var err error
+	if i.writeDuration, err = meter.Float64Histogram("rotatelogs.write.duration", metric.WithUnit("s"), metric.WithDescription("Duration of writes to log files.")); err != nil {
+		return nil, errors.Wrap(err, `failed to create write duration histogram`)
+	}
+	if i.writtenBytes, err = meter.Int64Counter("rotatelogs.written", metric.WithUnit("By"), metric.WithDescription("Number of bytes written to log files.")); err != nil {
+		return nil, errors.Wrap(err, `failed to create written bytes counter`)
+	}
+	if i.rotationDuration, err = meter.Float64Histogram("rotatelogs.rotation.duration", metric.WithUnit("s"), metric.WithDescription("Duration of log file rotations.")); err != nil {
+		return nil, errors.Wrap(err, `failed to create rotation duration histogram`)
+	}
+	if i.purgeDuration, err = meter.Float64Histogram("rotatelogs.purge.duration", metric.WithUnit("s"), metric.WithDescription("Duration of purges of old log files.")); err != nil {
+		return nil, errors.Wrap(err, `failed to create purge duration histogram`)
+	}
+	if i.purgedFiles, err = meter.Int64Counter("rotatelogs.purged", metric.WithUnit("{file}"), metric.WithDescription("Number of old log files removed.")); err != nil {
+		return nil, errors.Wrap(err, `failed to create purged files counter`)
+	}
+	if i.purgeFailures, err = meter.Int64Counter("rotatelogs.purge.failures", metric.WithUnit("{file}"), metric.WithDescription("Number of old log files that could not be removed.")); err != nil {
+		return nil, errors.Wrap(err, `failed to create purge failures counter`)
+	}
+
+	return i, nil
+}
+
+// withError returns the attributes of i along with the "error" attribute
+func (i *Instrumentation) withError(err error) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(i.attrs)+1)
+	attrs = append(attrs, i.attrs...)
+	attrs = append(attrs, attribute.Bool("error", err != nil))
+	return metric.WithAttributes(attrs...)
+}
+
+// span records a span for an operation that has already completed
+func (i *Instrumentation) span(name string, start time.Time, d time.Duration, err error, attrs ...attribute.KeyValue) {
+	_, span := i.tracer.Start(context.Background(), name,
+		trace.WithTimestamp(start),
+		trace.WithAttributes(i.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(start.Add(d)))
+}
+
+// OnWrite implements rotatelogs.Instrumentation
+func (i *Instrumentation) OnWrite(info rotatelogs.WriteInfo) {
+	ctx := context.Background()
+	i.writeDuration.Record(ctx, info.Duration.Seconds(), i.withError(info.Err))
+	i.writtenBytes.Add(ctx, int64(info.Bytes), metric.WithAttributes(i.attrs...))
+
+	if i.slowWriteThreshold > 0 && info.Duration >= i.slowWriteThreshold {
+		i.span("rotatelogs.write", info.Start, info.Duration, info.Err,
+			attribute.Int("rotatelogs.bytes", info.Bytes),
+		)
+	}
+}
+
+// OnRotate implements rotatelogs.Instrumentation
+func (i *Instrumentation) OnRotate(info rotatelogs.RotateInfo) {
+	i.rotationDuration.Record(context.Background(), info.Duration.Seconds(), i.withError(info.Err))
+	i.span("rotatelogs.rotate", info.Start, info.Duration, info.Err,
+		attribute.String("rotatelogs.previous_file", info.PreviousFile),
+		attribute.String("rotatelogs.current_file", info.CurrentFile),
+	)
+}
+
+// OnPurge implements rotatelogs.Instrumentation
+func (i *Instrumentation) OnPurge(info rotatelogs.PurgeInfo) {
+	ctx := context.Background()
+	i.purgeDuration.Record(ctx, info.Duration.Seconds(), i.withError(info.Err))
+	i.purgedFiles.Add(ctx, int64(len(info.Files)-info.Failures), metric.WithAttributes(i.attrs...))
+	i.purgeFailures.Add(ctx, int64(info.Failures), metric.WithAttributes(i.attrs...))
+	i.span("rotatelogs.purge", info.Start, info.Duration, info.Err,
+		attribute.Int("rotatelogs.files", len(info.Files)),
+		attribute.Int("rotatelogs.failures", info.Failures),
+	)
+}
@@ -0,0 +1,88 @@
+package otelinstrumentation_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/lestrrat-go/file-rotatelogs/otelinstrumentation"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInstrumentation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-otelinstrumentation")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	reader := sdkmetric.NewManualReader()
+	spans := tracetest.NewSpanRecorder()
+
+	instr, err := otelinstrumentation.New(
+		otelinstrumentation.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		otelinstrumentation.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		otelinstrumentation.WithAttributes(attribute.String("log", "test")),
+		otelinstrumentation.WithSlowWriteThreshold(time.Nanosecond),
+	)
+	if !assert.NoError(t, err, `otelinstrumentation.New should succeed`) {
+		return
+	}
+
+	rl, err := rotatelogs.New(filepath.Join(dir, "log"), rotatelogs.WithInstrumentation(instr))
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+
+	rl.Write([]byte("Hello, World!"))
+	if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+		return
+	}
+	if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+		return
+	}
+
+	var rm metricdata.ResourceMetrics
+	if !assert.NoError(t, reader.Collect(context.Background(), &rm), "reader.Collect should succeed") {
+		return
+	}
+
+	metrics := make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+
+	if written, ok := metrics["rotatelogs.written"]; assert.True(t, ok, "rotatelogs.written should be recorded") {
+		sum := written.Data.(metricdata.Sum[int64])
+		if assert.Len(t, sum.DataPoints, 1, "there should be one data point") {
+			assert.Equal(t, int64(13), sum.DataPoints[0].Value, "bytes written should match")
+			v, _ := sum.DataPoints[0].Attributes.Value("log")
+			assert.Equal(t, "test", v.AsString(), "attributes should be attached")
+		}
+	}
+
+	if rotation, ok := metrics["rotatelogs.rotation.duration"]; assert.True(t, ok, "rotatelogs.rotation.duration should be recorded") {
+		hist := rotation.Data.(metricdata.Histogram[float64])
+		if assert.Len(t, hist.DataPoints, 1, "there should be one data point") {
+			assert.Equal(t, uint64(1), hist.DataPoints[0].Count, "one rotation should be recorded")
+		}
+	}
+
+	names := make(map[string]int)
+	for _, span := range spans.Ended() {
+		names[span.Name()]++
+	}
+	assert.Equal(t, 1, names["rotatelogs.write"], "slow writes should produce spans")
+	assert.Equal(t, 1, names["rotatelogs.rotate"], "rotations should produce spans")
+}
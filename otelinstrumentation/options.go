@@ -0,0 +1,67 @@
+package otelinstrumentation
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option is used to pass optional arguments to New
+type Option interface {
+	Name() string
+	Value() interface{}
+}
+
+type option struct {
+	name  string
+	value interface{}
+}
+
+func newOption(name string, value interface{}) *option {
+	return &option{
+		name:  name,
+		value: value,
+	}
+}
+
+func (o *option) Name() string {
+	return o.name
+}
+
+func (o *option) Value() interface{} {
+	return o.value
+}
+
+const (
+	optkeyMeterProvider      = "meter-provider"
+	optkeyTracerProvider     = "tracer-provider"
+	optkeyAttributes         = "attributes"
+	optkeySlowWriteThreshold = "slow-write-threshold"
+)
+
+// WithMeterProvider sets the MeterProvider used to create metrics.
+// By default, the global MeterProvider is used.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return newOption(optkeyMeterProvider, mp)
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans.
+// By default, the global TracerProvider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return newOption(optkeyTracerProvider, tp)
+}
+
+// WithAttributes sets attributes that are added to all metrics and
+// spans, which can be used to tell several RotateLogs objects apart.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return newOption(optkeyAttributes, attrs)
+}
+
+// WithSlowWriteThreshold makes writes that take d or longer produce
+// a span, in addition to being recorded in the write duration metric.
+// By default, writes do not produce spans.
+func WithSlowWriteThreshold(d time.Duration) Option {
+	return newOption(optkeySlowWriteThreshold, d)
+}
@@ -0,0 +1,117 @@
+package rotatelogs
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// OversizePolicy determines what happens to writes that do not fit
+// in a file of the rotation size. See WithOversizePolicy
+type OversizePolicy int
+
+const (
+	oversizeUnset OversizePolicy = iota
+
+	// OversizeAllow writes data that is larger than the rotation size
+	// as is, to a file of its own
+	OversizeAllow
+
+	// OversizeSplitLines splits writes on line boundaries so that they
+	// fit in files of the rotation size. Lines are never split, so a
+	// single line larger than the rotation size is written to a file of
+	// its own
+	OversizeSplitLines
+)
+
+// curSizeNolock returns the size of the file currently being written to.
+// With WithOversizePolicy, it is tracked in memory rather than asking the
+// file system each time, unless other processes may be writing to the
+// file as well
+//
+// must be locked during this operation
+func (rl *RotateLogs) curSizeNolock(filename string) (int64, error) {
+	if rl.oversize != oversizeUnset && rl.lockFn == "" && rl.outFh != nil {
+		if rl.sizeFh != rl.outFh {
+			fi, err := rl.outFh.Stat()
+			if err != nil {
+				return 0, err
+			}
+			rl.curSize = fi.Size()
+			rl.sizeFh = rl.outFh
+		}
+		return rl.curSize, nil
+	}
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// hardLimit reports whether files are rotated before writes that would
+// make them exceed the rotation size, rather than after
+func (rl *RotateLogs) hardLimit() bool {
+	return rl.oversize != oversizeUnset
+}
+
+// writeSplitNolock writes p in chunks of complete lines that fit in the
+// remaining space of the current file, rotating it in between
+//
+// must be locked during this operation
+func (rl *RotateLogs) writeSplitNolock(p []byte) (int, error) {
+	var written int
+	var rotated bool
+	for len(p) > 0 {
+		// Open the file first, so that its header is accounted for
+		if _, err := rl.prepareWriterNolock(0); err != nil {
+			return written, err
+		}
+
+		size, err := rl.curSizeNolock(rl.curFn)
+		if err != nil {
+			size = 0
+		}
+
+		var chunk int
+		if room := rl.rotationSize - rl.footerSize - size; room > 0 {
+			chunk = linesPrefix(p, room)
+		}
+		if chunk == 0 && size > rl.headerSize && !rotated {
+			// Nothing fits in the current file, so start a new one
+			if _, err := rl.getWriterNolock(false, true, 0); err != nil {
+				return written, errors.Wrap(err, `failed to rotate`)
+			}
+			rotated = true
+			continue
+		}
+		if chunk == 0 {
+			// The first line is larger than an empty file can hold
+			chunk = len(p)
+			if i := bytes.IndexByte(p, '\n'); i >= 0 {
+				chunk = i + 1
+			}
+		}
+
+		n, err := rl.writeFileNolock(p[:chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[chunk:]
+		rotated = false
+	}
+
+	return written, nil
+}
+
+// linesPrefix returns the length of the longest prefix of p that is at
+// most max bytes long and does not end in the middle of a line
+func linesPrefix(p []byte, max int64) int {
+	if int64(len(p)) <= max {
+		return len(p)
+	}
+	return bytes.LastIndexByte(p[:max], '\n') + 1
+}
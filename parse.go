@@ -0,0 +1,144 @@
+package rotatelogs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sizeUnits maps (case-insensitive) size suffixes to their multipliers.
+// Units with a "B" suffix are decimal (SI), "iB" units are binary, and
+// single letter units are binary, as with logrotate
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"tib": 1 << 40,
+}
+
+// ParseSize parses a human readable size such as "100MB" or "512MiB"
+// into the number of bytes, suitable for WithRotationSize.
+//
+// Units are case-insensitive. "KB", "MB", "GB", and "TB" are decimal
+// (powers of 1000), while "KiB", "MiB", "GiB", and "TiB" are binary
+// (powers of 1024). Single letter units "K", "M", "G", and "T" are
+// binary as well, as with logrotate. Numbers without a unit, or with
+// the unit "B", are bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok || i == 0 {
+		return 0, errors.Errorf(`invalid size %q`, s)
+	}
+
+	if n, err := strconv.ParseInt(s[:i], 10, 64); err == nil {
+		if n > (1<<63-1)/unit {
+			return 0, errors.Errorf(`size %q is too large`, s)
+		}
+
+		return n * unit, nil
+	}
+
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, errors.Errorf(`invalid size %q`, s)
+	}
+
+	if f*float64(unit) >= 1<<63 {
+		return 0, errors.Errorf(`size %q is too large`, s)
+	}
+
+	return int64(f * float64(unit)), nil
+}
+
+// retentionSegmentRegexp matches a single number and unit pair in
+// a duration string, such as "14d" or "1.5h"
+var retentionSegmentRegexp = regexp.MustCompile(`^([0-9]*\.?[0-9]+|[0-9]+\.)([^0-9.]*)`)
+
+// ParseRetention parses a human readable duration such as "14d" or
+// "1w12h", suitable for WithMaxAge.
+//
+// In addition to the units accepted by time.ParseDuration, "d" (24
+// hours) and "w" (7 days) are supported. As with WithMaxAge, a negative
+// value such as "-1d" disables purging by age, which is only effective
+// together with WithRotationCount.
+func ParseRetention(s string) (time.Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+
+	var neg bool
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	if s == "" {
+		return 0, errors.Errorf(`invalid duration %q`, orig)
+	}
+
+	// days and weeks are handled here, everything else is collected
+	// and handed to time.ParseDuration
+	var d time.Duration
+	var rest strings.Builder
+	for s != "" {
+		m := retentionSegmentRegexp.FindStringSubmatch(s)
+		if m == nil {
+			return 0, errors.Errorf(`invalid duration %q`, orig)
+		}
+		s = s[len(m[0]):]
+
+		var unit time.Duration
+		switch m[2] {
+		case "d":
+			unit = 24 * time.Hour
+		case "w":
+			unit = 7 * 24 * time.Hour
+		default:
+			rest.WriteString(m[0])
+			continue
+		}
+
+		f, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, errors.Errorf(`invalid duration %q`, orig)
+		}
+		d += time.Duration(f * float64(unit))
+	}
+
+	if rest.Len() > 0 {
+		v, err := time.ParseDuration(rest.String())
+		if err != nil {
+			return 0, errors.Errorf(`invalid duration %q`, orig)
+		}
+		d += v
+	}
+
+	if neg {
+		d = -d
+	}
+
+	return d, nil
+}
@@ -0,0 +1,96 @@
+package rotatelogs_test
+
+import (
+	"testing"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected int64
+		Error    bool
+	}{
+		{Input: "1024", Expected: 1024},
+		{Input: "100B", Expected: 100},
+		{Input: "100MB", Expected: 100 * 1000 * 1000},
+		{Input: "512MiB", Expected: 512 * 1024 * 1024},
+		{Input: "512mib", Expected: 512 * 1024 * 1024},
+		{Input: "100M", Expected: 100 * 1024 * 1024},
+		{Input: "1.5 GiB", Expected: 1536 * 1024 * 1024},
+		{Input: "2TB", Expected: 2 * 1000 * 1000 * 1000 * 1000},
+		{Input: "", Error: true},
+		{Input: "MB", Error: true},
+		{Input: "100 bananas", Error: true},
+		{Input: "-1MB", Error: true},
+		{Input: "99999999999TiB", Error: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Input, func(t *testing.T) {
+			size, err := rotatelogs.ParseSize(tc.Input)
+			if tc.Error {
+				assert.Error(t, err, "rotatelogs.ParseSize should fail")
+
+				return
+			}
+
+			if !assert.NoError(t, err, "rotatelogs.ParseSize should succeed") {
+				return
+			}
+			assert.Equal(t, tc.Expected, size, "size should match")
+		})
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected time.Duration
+		Error    bool
+	}{
+		{Input: "14d", Expected: 14 * 24 * time.Hour},
+		{Input: "2w", Expected: 14 * 24 * time.Hour},
+		{Input: "1w12h", Expected: 7*24*time.Hour + 12*time.Hour},
+		{Input: "1.5d", Expected: 36 * time.Hour},
+		{Input: "90m", Expected: 90 * time.Minute},
+		{Input: "-1d", Expected: -24 * time.Hour},
+		{Input: "0", Expected: 0},
+		{Input: "", Error: true},
+		{Input: "14", Error: true},
+		{Input: "a fortnight", Error: true},
+		{Input: "1y", Error: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Input, func(t *testing.T) {
+			d, err := rotatelogs.ParseRetention(tc.Input)
+			if tc.Error {
+				assert.Error(t, err, "rotatelogs.ParseRetention should fail")
+
+				return
+			}
+
+			if !assert.NoError(t, err, "rotatelogs.ParseRetention should succeed") {
+				return
+			}
+			assert.Equal(t, tc.Expected, d, "duration should match")
+		})
+	}
+}
+
+func TestStringOptions(t *testing.T) {
+	_, err := rotatelogs.New("log%Y%m%d", rotatelogs.WithMaxAgeString("14d"), rotatelogs.WithRotationSizeString("512MiB"))
+	assert.NoError(t, err, "rotatelogs.New should succeed")
+
+	_, err = rotatelogs.New("log%Y%m%d", rotatelogs.WithMaxAgeString("a fortnight"))
+	assert.Error(t, err, "rotatelogs.New should fail")
+
+	_, err = rotatelogs.New("log%Y%m%d", rotatelogs.WithRotationSizeString("100 bananas"))
+	assert.Error(t, err, "rotatelogs.New should fail")
+}
@@ -0,0 +1,145 @@
+package rotatelogs
+
+import (
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// placeholderRegexp matches placeholders such as %{hostname} in patterns
+var placeholderRegexp = regexp.MustCompile(`%\{([^{}]*)\}`)
+
+// patternVar is the value of an optkeyPatternVar option
+type patternVar struct {
+	name string
+	fn   func() string
+}
+
+// builtinPatternVars returns the placeholders that are always available
+func builtinPatternVars() map[string]func() string {
+	return map[string]func() string{
+		"hostname": func() string {
+			hostname, err := os.Hostname()
+			if err != nil || hostname == "" {
+				return "localhost"
+			}
+			return hostname
+		},
+		"pid": func() string {
+			return strconv.Itoa(os.Getpid())
+		},
+	}
+}
+
+// patternVars holds the values of the placeholders used by a
+// RotateLogs object. Each value is computed at most once
+type patternVars struct {
+	fns    map[string]func() string
+	values map[string]string
+}
+
+func newPatternVars(fns map[string]func() string) *patternVars {
+	return &patternVars{
+		fns:    fns,
+		values: make(map[string]string),
+	}
+}
+
+func (v *patternVars) value(name string) (string, error) {
+	if value, ok := v.values[name]; ok {
+		return value, nil
+	}
+
+	fn, ok := v.fns[name]
+	if !ok {
+		return "", withSentinel(ErrInvalidPattern, errors.Errorf(`unknown placeholder %%{%s}`, name))
+	}
+	value := fn()
+	v.values[name] = value
+	return value, nil
+}
+
+// expand replaces the placeholders in s with their values, passed
+// through escape. Parts of s outside of placeholders are passed
+// through literal
+func (v *patternVars) expand(s string, literal, escape func(string) string) (string, error) {
+	var buf strings.Builder
+	last := 0
+	for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(s, -1) {
+		value, err := v.value(s[m[2]:m[3]])
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(literal(s[last:m[0]]))
+		buf.WriteString(escape(value))
+		last = m[1]
+	}
+	buf.WriteString(literal(s[last:]))
+	return buf.String(), nil
+}
+
+// expandFilename replaces the placeholders in a plain file name
+func (v *patternVars) expandFilename(s string) (string, error) {
+	return v.expand(s, verbatim, verbatim)
+}
+
+// expandPattern replaces the placeholders in a strftime pattern, and
+// returns the resulting strftime pattern along with a glob pattern that
+// matches the files it generates
+func (v *patternVars) expandPattern(p string) (string, string, error) {
+	pattern, err := v.expand(p, verbatim, escapeStrftime)
+	if err != nil {
+		return "", "", err
+	}
+
+	globPattern, err := v.expand(p, strftimeToGlob, escapeGlob)
+	if err != nil {
+		return "", "", err
+	}
+
+	return pattern, globPattern, nil
+}
+
+func verbatim(s string) string {
+	return s
+}
+
+func escapeStrftime(s string) string {
+	return strings.Replace(s, "%", "%%", -1)
+}
+
+// strftimeToGlob replaces strftime verbs with wildcards
+func strftimeToGlob(s string) string {
+	for _, re := range patternConversionRegexps {
+		s = re.ReplaceAllString(s, "*")
+	}
+	return s
+}
+
+// escapeGlob makes s match itself in a glob pattern. Windows does not
+// support escaping in glob patterns, so special characters are matched
+// by wildcards instead
+func escapeGlob(s string) string {
+	var buf strings.Builder
+	for _, r := range s {
+		if runtime.GOOS == "windows" {
+			switch r {
+			case '*', '?', '[':
+				r = '?'
+			}
+			buf.WriteRune(r)
+			continue
+		}
+
+		switch r {
+		case '*', '?', '[', '\\':
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
@@ -0,0 +1,207 @@
+package rotatelogs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/file-rotatelogs/internal/fileutil"
+	"github.com/pkg/errors"
+)
+
+// When multiple processes write to the same pattern, they coordinate
+// via a process lock file (see WithProcessLock). An advisory lock on
+// this file is held by the process that rotates the logs, and its
+// contents record the file that is currently being written to, so that
+// other processes can follow instead of rotating on their own.
+//
+// Advisory locks are released by the kernel when the process exits,
+// so a crashed process never leaves a stale lock behind.
+
+// sharedState is the content of the process lock file
+type sharedState struct {
+	baseFn     string // file name generated from the pattern
+	filename   string // file name being written to, possibly generational
+	generation int    // generation of filename
+}
+
+// lockProcessNolock acquires the advisory lock on the process lock
+// file, opening it if necessary
+//
+// must be locked during this operation
+func (rl *RotateLogs) lockProcessNolock(exclusive bool) error {
+	if rl.lockFh == nil {
+		dirname := filepath.Dir(rl.lockFn)
+		if err := os.MkdirAll(dirname, rl.dirMode); err != nil {
+			return errors.Wrapf(err, `failed to create directory %s`, dirname)
+		}
+
+		fh, err := os.OpenFile(rl.lockFn, os.O_CREATE|os.O_RDWR, rl.fileMode)
+		if err != nil {
+			return errors.Wrapf(err, `failed to open process lock file %s`, rl.lockFn)
+		}
+		rl.lockFh = fh
+	}
+
+	return fileutil.Flock(rl.lockFh, exclusive)
+}
+
+// unlockProcessNolock releases the advisory lock on the process lock file
+//
+// must be locked during this operation
+func (rl *RotateLogs) unlockProcessNolock() {
+	// remember what the file looked like while we held the lock,
+	// so that we can cheaply detect changes by other processes
+	if fi, err := rl.lockFh.Stat(); err == nil {
+		rl.lockModTime = fi.ModTime()
+		rl.lockSize = fi.Size()
+	}
+
+	if err := fileutil.Funlock(rl.lockFh); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+}
+
+// readSharedStateNolock reads the contents of the process lock file.
+// An empty sharedState is returned if no process has written to it yet
+//
+// must be locked during this operation, while holding the process lock
+func (rl *RotateLogs) readSharedStateNolock() (sharedState, error) {
+	var st sharedState
+
+	if _, err := rl.lockFh.Seek(0, io.SeekStart); err != nil {
+		return st, errors.Wrapf(err, `failed to read process lock file %s`, rl.lockFn)
+	}
+
+	var generation string
+	scanner := bufio.NewScanner(rl.lockFh)
+	for _, dst := range []*string{&st.baseFn, &st.filename, &generation} {
+		if !scanner.Scan() {
+			break
+		}
+		*dst = strings.TrimSpace(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return st, errors.Wrapf(err, `failed to read process lock file %s`, rl.lockFn)
+	}
+
+	// the generation is missing from lock files written by older versions
+	if generation != "" {
+		g, err := strconv.Atoi(generation)
+		if err != nil {
+			return st, errors.Wrapf(err, `invalid generation in process lock file %s`, rl.lockFn)
+		}
+		st.generation = g
+	}
+
+	return st, nil
+}
+
+// writeSharedStateNolock records the file that we are writing to in
+// the process lock file
+//
+// must be locked during this operation, while holding an exclusive
+// process lock
+func (rl *RotateLogs) writeSharedStateNolock() error {
+	if err := rl.lockFh.Truncate(0); err != nil {
+		return errors.Wrapf(err, `failed to truncate process lock file %s`, rl.lockFn)
+	}
+
+	if _, err := rl.lockFh.WriteAt([]byte(fmt.Sprintf("%s\n%s\n%d\n", rl.curBaseFn, rl.curFn, rl.generation)), 0); err != nil {
+		return errors.Wrapf(err, `failed to write process lock file %s`, rl.lockFn)
+	}
+
+	return nil
+}
+
+// shouldFollowNolock returns true if another process has already
+// moved on to a new file for the period denoted by baseFn, and we
+// should start writing to it instead of rotating ourselves
+//
+// must be locked during this operation
+func (rl *RotateLogs) shouldFollowNolock(st sharedState, baseFn string, incoming int) bool {
+	if st.filename == "" || st.baseFn != baseFn || st.filename == rl.curFn {
+		return false
+	}
+
+	// ForceNewFile requests a new file on the first write, regardless
+	// of what other processes are doing
+	if rl.curFn == "" && rl.forceNewFile {
+		return false
+	}
+
+	fi, err := os.Stat(st.filename)
+	if err != nil {
+		return false
+	}
+
+	// the other process' file is already due for rotation
+	if rl.sizeDueNolock(fi.Size(), incoming) {
+		return false
+	}
+
+	return true
+}
+
+// followNolock switches over to the file recorded in the process lock
+// file. Unlike a rotation, no symlinks are updated, no files are purged,
+// and no events are emitted, as that is the job of the process that
+// actually rotated the logs
+//
+// must be locked during this operation
+func (rl *RotateLogs) followNolock(st sharedState) (io.Writer, error) {
+	fh, err := rl.createFileNolock(st.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rl.closeFileNolock(rl.outFh); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+	rl.outFh = fh
+	rl.curBaseFn = st.baseFn
+	rl.curFn = st.filename
+	rl.generation = st.generation
+	rl.startFileNolock(st.filename, 0)
+
+	return fh, nil
+}
+
+// followProcessesNolock checks if another process has rotated the logs
+// since we last looked at the process lock file, and if so, switches
+// over to the new file. Only enabled by WithProcessLock
+//
+// must be locked during this operation
+func (rl *RotateLogs) followProcessesNolock() error {
+	if rl.lockFn == "" || rl.outFh == nil {
+		return nil
+	}
+
+	fi, err := os.Stat(rl.lockFn)
+	if err != nil || (fi.ModTime().Equal(rl.lockModTime) && fi.Size() == rl.lockSize) {
+		return nil
+	}
+
+	if err := rl.lockProcessNolock(false); err != nil {
+		return errors.Wrap(err, `failed to acquire process lock`)
+	}
+	defer rl.unlockProcessNolock()
+
+	st, err := rl.readSharedStateNolock()
+	if err != nil {
+		return err
+	}
+
+	if !rl.shouldFollowNolock(st, rl.curBaseFn, 0) {
+		return nil
+	}
+
+	_, err = rl.followNolock(st)
+
+	return err
+}
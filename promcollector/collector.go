@@ -0,0 +1,96 @@
+// Package promcollector exposes the statistics of a RotateLogs object
+// as Prometheus metrics.
+package promcollector
+
+import (
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "rotatelogs"
+
+// Collector is a prometheus.Collector that reports the Stats of a
+// RotateLogs object each time it is scraped.
+type Collector struct {
+	rl *rotatelogs.RotateLogs
+
+	bytesWritten      *prometheus.Desc
+	writes            *prometheus.Desc
+	writeErrors       *prometheus.Desc
+	writeSeconds      *prometheus.Desc
+	rotations         *prometheus.Desc
+	lastRotation      *prometheus.Desc
+	purges            *prometheus.Desc
+	purgeFailures     *prometheus.Desc
+	currentFileSize   *prometheus.Desc
+	currentGeneration *prometheus.Desc
+	mirrorErrors      *prometheus.Desc
+	mirrorDrops       *prometheus.Desc
+}
+
+// New creates a Collector for rl. constLabels are attached to every
+// metric, and can be used to tell several RotateLogs objects apart
+// when they are registered with the same registry. constLabels may
+// be nil.
+//
+//	prometheus.MustRegister(promcollector.New(rl, prometheus.Labels{"log": "access"}))
+func New(rl *rotatelogs.RotateLogs, constLabels prometheus.Labels) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, constLabels)
+	}
+
+	return &Collector{
+		rl:                rl,
+		bytesWritten:      desc("written_bytes_total", "Number of bytes written to log files."),
+		writes:            desc("writes_total", "Number of calls to Write."),
+		writeErrors:       desc("write_errors_total", "Number of calls to Write that failed."),
+		writeSeconds:      desc("write_seconds_total", "Total time spent in Write, in seconds."),
+		rotations:         desc("rotations_total", "Number of log file rotations."),
+		lastRotation:      desc("last_rotation_timestamp_seconds", "Time of the last log file rotation, in seconds since the epoch."),
+		purges:            desc("purged_files_total", "Number of old log files removed."),
+		purgeFailures:     desc("purge_failures_total", "Number of old log files that could not be removed."),
+		currentFileSize:   desc("current_file_size_bytes", "Size of the log file currently being written to."),
+		currentGeneration: desc("current_generation", "Generation of the log file currently being written to."),
+		mirrorErrors:      desc("mirror_errors_total", "Number of writes that failed on a mirror."),
+		mirrorDrops:       desc("mirror_drops_total", "Number of writes dropped because a mirror was falling behind."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.bytesWritten
+	ch <- c.writes
+	ch <- c.writeErrors
+	ch <- c.writeSeconds
+	ch <- c.rotations
+	ch <- c.lastRotation
+	ch <- c.purges
+	ch <- c.purgeFailures
+	ch <- c.currentFileSize
+	ch <- c.currentGeneration
+	ch <- c.mirrorErrors
+	ch <- c.mirrorDrops
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.rl.Stats()
+
+	var lastRotation float64
+	if !st.LastRotation.IsZero() {
+		lastRotation = float64(st.LastRotation.UnixNano()) / 1e9
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.bytesWritten, prometheus.CounterValue, float64(st.BytesWritten))
+	ch <- prometheus.MustNewConstMetric(c.writes, prometheus.CounterValue, float64(st.Writes))
+	ch <- prometheus.MustNewConstMetric(c.writeErrors, prometheus.CounterValue, float64(st.WriteErrors))
+	ch <- prometheus.MustNewConstMetric(c.writeSeconds, prometheus.CounterValue, st.WriteLatency.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.rotations, prometheus.CounterValue, float64(st.Rotations))
+	ch <- prometheus.MustNewConstMetric(c.lastRotation, prometheus.GaugeValue, lastRotation)
+	ch <- prometheus.MustNewConstMetric(c.purges, prometheus.CounterValue, float64(st.Purges))
+	ch <- prometheus.MustNewConstMetric(c.purgeFailures, prometheus.CounterValue, float64(st.PurgeFailures))
+	ch <- prometheus.MustNewConstMetric(c.currentFileSize, prometheus.GaugeValue, float64(st.CurrentFileSize))
+	ch <- prometheus.MustNewConstMetric(c.currentGeneration, prometheus.GaugeValue, float64(st.CurrentGeneration))
+	ch <- prometheus.MustNewConstMetric(c.mirrorErrors, prometheus.CounterValue, float64(st.MirrorErrors))
+	ch <- prometheus.MustNewConstMetric(c.mirrorDrops, prometheus.CounterValue, float64(st.MirrorDrops))
+}
@@ -0,0 +1,52 @@
+package promcollector_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/lestrrat-go/file-rotatelogs/promcollector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-promcollector")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	rl, err := rotatelogs.New(filepath.Join(dir, "log"))
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+	defer rl.Close()
+
+	rl.Write([]byte("Hello, World!"))
+
+	c := promcollector.New(rl, prometheus.Labels{"log": "test"})
+	assert.Equal(t, 12, testutil.CollectAndCount(c), "all metrics should be collected")
+
+	expected := `
+# HELP rotatelogs_written_bytes_total Number of bytes written to log files.
+# TYPE rotatelogs_written_bytes_total counter
+rotatelogs_written_bytes_total{log="test"} 13
+# HELP rotatelogs_current_file_size_bytes Size of the log file currently being written to.
+# TYPE rotatelogs_current_file_size_bytes gauge
+rotatelogs_current_file_size_bytes{log="test"} 13
+# HELP rotatelogs_rotations_total Number of log file rotations.
+# TYPE rotatelogs_rotations_total counter
+rotatelogs_rotations_total{log="test"} 0
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"rotatelogs_written_bytes_total",
+		"rotatelogs_current_file_size_bytes",
+		"rotatelogs_rotations_total",
+	)
+	assert.NoError(t, err, "metrics should match")
+}
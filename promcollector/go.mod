@@ -0,0 +1,14 @@
+module github.com/lestrrat-go/file-rotatelogs/promcollector
+
+go 1.13
+
+require (
+	github.com/lestrrat-go/file-rotatelogs v0.0.0-20261018234818-81f469e3573c
+	github.com/prometheus/client_golang v1.12.2
+	github.com/stretchr/testify v1.4.0
+)
+
+// The root module is taken from this repository during development.
+// Users of this module get the version required above instead, since
+// replace directives only apply to the main module (see RELEASING.md)
+replace github.com/lestrrat-go/file-rotatelogs => ../
@@ -0,0 +1,84 @@
+package rotatelogs
+
+import (
+	"sync"
+)
+
+// purger removes old files on a single background goroutine on behalf
+// of several RotateLogs objects, instead of each rotation spawning its
+// own goroutine. It is shared by the objects created by a Manager
+type purger struct {
+	mutex   sync.Mutex
+	queue   []func()
+	wakeup  chan struct{}
+	stopped bool
+	done    chan struct{}
+}
+
+func newPurger() *purger {
+	p := &purger{
+		wakeup: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// submit queues job to be run by the purger. It never blocks. Once the
+// purger has been stopped, jobs are run on their own goroutine instead
+func (p *purger) submit(job func()) {
+	p.mutex.Lock()
+	if p.stopped {
+		p.mutex.Unlock()
+		go job()
+		return
+	}
+	p.queue = append(p.queue, job)
+	p.mutex.Unlock()
+
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (p *purger) run() {
+	defer close(p.done)
+	for {
+		p.mutex.Lock()
+		queue := p.queue
+		p.queue = nil
+		stopped := p.stopped
+		p.mutex.Unlock()
+
+		for _, job := range queue {
+			job()
+		}
+
+		if len(queue) > 0 {
+			continue
+		}
+		if stopped {
+			return
+		}
+		<-p.wakeup
+	}
+}
+
+// stop waits until all queued jobs have been run, and stops the purger
+func (p *purger) stop() {
+	p.mutex.Lock()
+	if p.stopped {
+		p.mutex.Unlock()
+		<-p.done
+		return
+	}
+	p.stopped = true
+	p.mutex.Unlock()
+
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+	<-p.done
+}
@@ -0,0 +1,104 @@
+package rotatelogs
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reconfigure changes the rotation settings of a live RotateLogs object
+// without closing the current file. Only the following options are
+// accepted: WithRotationTime, WithRotationSize, WithRotationSizeString,
+// WithMaxAge, WithMaxAgeString, WithRotationCount and WithLinkName.
+// Any other option results in an error.
+//
+// The options are validated as a whole before anything is changed, so
+// if an error is returned the object is left untouched. New values take
+// effect from the next write: for example, changing the rotation time
+// may cause the next write to go to a new file, and a new retention
+// policy is applied on the next purge. If the link name changes, the
+// new link is created immediately and the old one is removed. As with
+// New, placeholders such as "%{hostname}" are expanded in the link name.
+func (rl *RotateLogs) Reconfigure(options ...Option) error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.closed {
+		return ErrClosed
+	}
+
+	rotationTime := rl.rotationTime
+	rotationSize := rl.rotationSize
+	rotationCount := rl.rotationCount
+	linkName := rl.linkName
+	maxAge := rl.maxAge
+	var maxAgeSet, rotationCountSet bool
+
+	for _, o := range options {
+		var err error
+		switch o.Name() {
+		case optkeyRotationTime:
+			rotationTime = rotationTimeValue(o)
+		case optkeyRotationSize:
+			rotationSize, err = rotationSizeValue(o)
+		case optkeyMaxAge:
+			maxAge, err = maxAgeValue(o)
+			maxAgeSet = true
+		case optkeyRotationCount:
+			rotationCount = o.Value().(uint)
+			rotationCountSet = true
+		case optkeyLinkName:
+			linkName, err = rl.vars.expandFilename(o.Value().(string))
+		default:
+			err = errors.Errorf("option %s cannot be changed by Reconfigure", o.Name())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Switching from one retention policy to the other is allowed
+	// without having to explicitly disable the previous one
+	if maxAgeSet && !rotationCountSet && maxAge > 0 {
+		rotationCount = 0
+	}
+	if rotationCountSet && !maxAgeSet && rotationCount > 0 {
+		maxAge = 0
+	}
+
+	if maxAge > 0 && rotationCount > 0 {
+		return withSentinel(ErrConflictingOptions, errors.New("options MaxAge and RotationCount cannot be both set"))
+	}
+
+	if maxAge == 0 && rotationCount == 0 {
+		// same default as New
+		maxAge = 7 * 24 * time.Hour
+	}
+
+	if linkName != rl.linkName {
+		// The new link is created first, so that nothing is changed
+		// if that fails
+		oldLinkName := rl.linkName
+		rl.linkName = linkName
+		if linkName != "" && rl.outFh != nil {
+			if err := rl.linkNolock(rl.curFn); err != nil {
+				rl.linkName = oldLinkName
+				return err
+			}
+		}
+
+		if oldLinkName != "" {
+			if fi, err := os.Lstat(oldLinkName); err == nil && fi.Mode()&os.ModeSymlink == os.ModeSymlink {
+				os.Remove(oldLinkName)
+			}
+		}
+	}
+
+	rl.rotationTime = rotationTime
+	rl.rotationSize = rotationSize
+	rl.rotationCount = rotationCount
+	rl.maxAge = maxAge
+
+	return nil
+}
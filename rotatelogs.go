@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,16 +27,7 @@ func (c clockFn) Now() time.Time {
 // New creates a new RotateLogs object. A log filename pattern
 // must be passed. Optional `Option` parameters may be passed
 func New(p string, options ...Option) (*RotateLogs, error) {
-	globPattern := p
-	for _, re := range patternConversionRegexps {
-		globPattern = re.ReplaceAllString(globPattern, "*")
-	}
-
-	pattern, err := strftime.New(p)
-	if err != nil {
-		return nil, errors.Wrap(err, `invalid strftime pattern`)
-	}
-
+	var err error
 	var clock Clock = Local
 	rotationTime := 24 * time.Hour
 	var rotationSize int64
@@ -44,6 +36,23 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 	var maxAge time.Duration
 	var handler Handler
 	var forceNewFile bool
+	var strategy rotationStrategy
+	var stableFn string
+	var genFormatter GenerationFormatter = GenerationSuffix
+	var fileMode os.FileMode = 0644
+	var dirMode os.FileMode = 0755
+	var owner *fileOwner
+	var watchFile bool
+	var watchInterval time.Duration
+	var lockFn string
+	lockTimeout := time.Minute
+	syncPolicy := SyncNever
+	var instrument Instrumentation
+	varFns := builtinPatternVars()
+	var mirrors []io.Writer
+	var lineAtomic bool
+	var oversize OversizePolicy
+	var header, footer func(FileInfo) []byte
 
 	for _, o := range options {
 		switch o.Name() {
@@ -52,19 +61,16 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 		case optkeyLinkName:
 			linkName = o.Value().(string)
 		case optkeyMaxAge:
-			maxAge = o.Value().(time.Duration)
-			if maxAge < 0 {
-				maxAge = 0
+			maxAge, err = maxAgeValue(o)
+			if err != nil {
+				return nil, err
 			}
 		case optkeyRotationTime:
-			rotationTime = o.Value().(time.Duration)
-			if rotationTime < 0 {
-				rotationTime = 0
-			}
+			rotationTime = rotationTimeValue(o)
 		case optkeyRotationSize:
-			rotationSize = o.Value().(int64)
-			if rotationSize < 0 {
-				rotationSize = 0
+			rotationSize, err = rotationSizeValue(o)
+			if err != nil {
+				return nil, err
 			}
 		case optkeyRotationCount:
 			rotationCount = o.Value().(uint)
@@ -72,11 +78,92 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 			handler = o.Value().(Handler)
 		case optkeyForceNewFile:
 			forceNewFile = true
+		case optkeyRenameOnRotate:
+			if strategy != strategyDirect && strategy != strategyRename {
+				return nil, withSentinel(ErrConflictingOptions, errors.New("options RenameOnRotate and CopyTruncate cannot be both set"))
+			}
+			strategy = strategyRename
+			stableFn = o.Value().(string)
+		case optkeyCopyTruncate:
+			if strategy != strategyDirect && strategy != strategyCopyTruncate {
+				return nil, withSentinel(ErrConflictingOptions, errors.New("options RenameOnRotate and CopyTruncate cannot be both set"))
+			}
+			strategy = strategyCopyTruncate
+			stableFn = o.Value().(string)
+		case optkeyGenerationFormat:
+			genFormatter = o.Value().(GenerationFormatter)
+		case optkeyFileMode:
+			fileMode = o.Value().(os.FileMode)
+		case optkeyDirMode:
+			dirMode = o.Value().(os.FileMode)
+		case optkeyOwner:
+			owner = o.Value().(*fileOwner)
+		case optkeyFileWatch:
+			watchFile = true
+			watchInterval = o.Value().(time.Duration)
+		case optkeyProcessLock:
+			lockFn = o.Value().(string)
+		case optkeyStaleLockTimeout:
+			lockTimeout = o.Value().(time.Duration)
+		case optkeySyncPolicy:
+			syncPolicy = o.Value().(SyncPolicy)
+		case optkeyInstrumentation:
+			instrument = o.Value().(Instrumentation)
+		case optkeyLineAtomic:
+			lineAtomic = o.Value().(bool)
+		case optkeyOversizePolicy:
+			oversize = o.Value().(OversizePolicy)
+		case optkeyFileHeader:
+			header = o.Value().(func(FileInfo) []byte)
+		case optkeyFileFooter:
+			footer = o.Value().(func(FileInfo) []byte)
+		case optkeyMirror:
+			mirrors = append(mirrors, o.Value().(io.Writer))
+		case optkeyPatternVar:
+			v := o.Value().(*patternVar)
+			varFns[v.name] = v.fn
+		}
+	}
+
+	vars := newPatternVars(varFns)
+	expandedP, globPattern, err := vars.expandPattern(p)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := strftime.New(expandedP)
+	if err != nil {
+		return nil, withSentinel(ErrInvalidPattern, errors.Wrap(err, `invalid strftime pattern`))
+	}
+
+	for _, fn := range []*string{&linkName, &stableFn, &lockFn} {
+		if *fn, err = vars.expandFilename(*fn); err != nil {
+			return nil, err
 		}
 	}
 
 	if maxAge > 0 && rotationCount > 0 {
-		return nil, errors.New("options MaxAge and RotationCount cannot be both set")
+		return nil, withSentinel(ErrConflictingOptions, errors.New("options MaxAge and RotationCount cannot be both set"))
+	}
+
+	if syncPolicy.mode == syncModeInterval && syncPolicy.interval <= 0 {
+		return nil, errors.Errorf("sync interval must be positive (got %s)", syncPolicy.interval)
+	}
+
+	if owner != nil && !fileutil.ChownSupported {
+		return nil, withSentinel(ErrUnsupported, errors.New("option Owner is only supported on Linux"))
+	}
+
+	if lockFn != "" && !fileutil.FlockSupported {
+		return nil, withSentinel(ErrUnsupported, errors.New("option ProcessLock is only supported on Unix-like platforms"))
+	}
+
+	if strategy != strategyDirect && stableFn == "" {
+		return nil, errors.New("file name for rotation strategy must not be empty")
+	}
+
+	if strategy != strategyDirect && lockFn != "" {
+		return nil, withSentinel(ErrConflictingOptions, errors.New("option ProcessLock cannot be used with RenameOnRotate or CopyTruncate"))
 	}
 
 	if maxAge == 0 && rotationCount == 0 {
@@ -84,18 +171,94 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 		maxAge = 7 * 24 * time.Hour
 	}
 
-	return &RotateLogs{
+	rl := &RotateLogs{
 		clock:         clock,
 		eventHandler:  handler,
 		globPattern:   globPattern,
 		linkName:      linkName,
+		vars:          vars,
 		maxAge:        maxAge,
 		pattern:       pattern,
 		rotationTime:  rotationTime,
 		rotationSize:  rotationSize,
 		rotationCount: rotationCount,
 		forceNewFile:  forceNewFile,
-	}, nil
+		strategy:      strategy,
+		stableFn:      stableFn,
+		genFormatter:  genFormatter,
+		fileMode:      fileMode,
+		dirMode:       dirMode,
+		owner:         owner,
+		watchFile:     watchFile,
+		watchInterval: watchInterval,
+		lockFn:        lockFn,
+		lockTimeout:   lockTimeout,
+		syncPolicy:    syncPolicy,
+		instrument:    instrument,
+		lineAtomic:    lineAtomic,
+		oversize:      oversize,
+		header:        header,
+		footer:        footer,
+		done:          make(chan struct{}),
+	}
+
+	for _, w := range mirrors {
+		rl.startMirror(w)
+	}
+
+	if syncPolicy.mode == syncModeInterval {
+		rl.syncStop = make(chan struct{})
+		go rl.runSyncInterval(syncPolicy.interval, rl.syncStop)
+	}
+
+	return rl, nil
+}
+
+// maxAgeValue returns the value of a MaxAge option
+func maxAgeValue(o Option) (time.Duration, error) {
+	var maxAge time.Duration
+	switch v := o.Value().(type) {
+	case time.Duration:
+		maxAge = v
+	case string:
+		d, err := ParseRetention(v)
+		if err != nil {
+			return 0, errors.Wrap(err, `invalid value for option MaxAge`)
+		}
+		maxAge = d
+	}
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	return maxAge, nil
+}
+
+// rotationTimeValue returns the value of a RotationTime option
+func rotationTimeValue(o Option) time.Duration {
+	rotationTime := o.Value().(time.Duration)
+	if rotationTime < 0 {
+		rotationTime = 0
+	}
+	return rotationTime
+}
+
+// rotationSizeValue returns the value of a RotationSize option
+func rotationSizeValue(o Option) (int64, error) {
+	var rotationSize int64
+	switch v := o.Value().(type) {
+	case int64:
+		rotationSize = v
+	case string:
+		size, err := ParseSize(v)
+		if err != nil {
+			return 0, errors.Wrap(err, `invalid value for option RotationSize`)
+		}
+		rotationSize = size
+	}
+	if rotationSize < 0 {
+		rotationSize = 0
+	}
+	return rotationSize, nil
 }
 
 // Write satisfies the io.Writer interface. It writes to the
@@ -103,20 +266,157 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 // If we have reached rotation time, the target file gets
 // automatically rotated, and also purged if necessary.
 func (rl *RotateLogs) Write(p []byte) (n int, err error) {
+	start := time.Now()
+	defer func() {
+		latency := time.Since(start)
+		rl.stats.recordWrite(n, err, latency)
+		if rl.instrument != nil {
+			rl.instrument.OnWrite(WriteInfo{
+				Start:    start,
+				Duration: latency,
+				Bytes:    n,
+				Err:      err,
+			})
+		}
+	}()
+
 	// Guard against concurrent writes
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
-	out, err := rl.getWriterNolock(false, false)
+	if rl.closed {
+		return 0, ErrClosed
+	}
+
+	// Mirrors get everything, even if writing to the file fails
+	defer rl.mirrorNolock(p)
+
+	if rl.lineAtomic {
+		return rl.writeLinesNolock(p)
+	}
+
+	return rl.writeNolock(p)
+}
+
+// writeNolock writes p to the log files
+//
+// must be locked during this operation
+func (rl *RotateLogs) writeNolock(p []byte) (int, error) {
+	if rl.oversize == OversizeSplitLines && rl.rotationSize > 0 {
+		return rl.writeSplitNolock(p)
+	}
+
+	return rl.writeFileNolock(p)
+}
+
+// writeFileNolock writes p to the current file, rotating it first if
+// necessary
+//
+// must be locked during this operation
+func (rl *RotateLogs) writeFileNolock(p []byte) (n int, err error) {
+	out, err := rl.prepareWriterNolock(len(p))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err = out.Write(p)
+	if rl.sizeFh != nil && rl.sizeFh == rl.outFh {
+		rl.curSize += int64(n)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	switch rl.syncPolicy.mode {
+	case syncModeEveryWrite:
+		if err := rl.syncNolock(); err != nil {
+			return n, err
+		}
+	case syncModeInterval:
+		rl.dirty = true
+	}
+
+	return n, nil
+}
+
+// prepareWriterNolock returns the file that incoming bytes should be
+// written to, reopening, following or rotating files as necessary
+//
+// must be locked during this operation
+func (rl *RotateLogs) prepareWriterNolock(incoming int) (io.Writer, error) {
+	if err := rl.watchFileNolock(); err != nil {
+		return nil, err
+	}
+
+	if err := rl.followProcessesNolock(); err != nil {
+		return nil, err
+	}
+
+	out, err := rl.getWriterNolock(false, false, incoming)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to acquite target io.Writer`)
+	}
+
+	return out, nil
+}
+
+// watchFileNolock checks if the file that we are writing to has been
+// removed or renamed by somebody else, and if so, reopens it.
+// Only enabled by WithFileWatch.
+//
+// must be locked during this operation
+func (rl *RotateLogs) watchFileNolock() error {
+	if !rl.watchFile || rl.outFh == nil {
+		return nil
+	}
+
+	// The interval is measured in real time, as the clock may be
+	// replaced by the user
+	now := time.Now()
+	if rl.watchInterval > 0 && now.Sub(rl.lastWatch) < rl.watchInterval {
+		return nil
+	}
+	rl.lastWatch = now
+
+	if fi, err := os.Stat(rl.curFn); err == nil {
+		if cur, err := rl.outFh.Stat(); err == nil && os.SameFile(fi, cur) {
+			return nil
+		}
+	}
+
+	return rl.reopenNolock()
+}
+
+// reopenNolock closes the current file handle and opens rl.curFn
+// again, creating it if necessary
+//
+// must be locked during this operation
+func (rl *RotateLogs) reopenNolock() error {
+	fh, err := rl.createFileNolock(rl.curFn)
 	if err != nil {
-		return 0, errors.Wrap(err, `failed to acquite target io.Writer`)
+		return errors.Wrap(err, `failed to reopen file`)
 	}
 
-	return out.Write(p)
+	headerSize, err := rl.writeHeaderNolock(fh, rl.curFn, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+
+	if err := rl.closeFileNolock(rl.outFh); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+	rl.outFh = fh
+	rl.startFileNolock(rl.curFn, headerSize)
+
+	return nil
 }
 
 // must be locked during this operation
-func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames bool) (io.Writer, error) {
+func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames bool, incoming int) (_ io.Writer, err error) {
+	if rl.strategy != strategyDirect {
+		return rl.getStableWriterNolock(bailOnRotateFail, useGenerationalNames, incoming)
+	}
+
 	generation := rl.generation
 	previousFn := rl.curFn
 
@@ -126,9 +426,9 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 	filename := baseFn
 	var forceNewFile bool
 
-	fi, err := os.Stat(rl.curFn)
+	size, err := rl.curSizeNolock(rl.curFn)
 	sizeRotation := false
-	if err == nil && rl.rotationSize > 0 && rl.rotationSize <= fi.Size() {
+	if err == nil && rl.sizeDueNolock(size, incoming) {
 		forceNewFile = true
 		sizeRotation = true
 	}
@@ -140,6 +440,13 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 		if rl.forceNewFile {
 			forceNewFile = true
 		}
+		// The file may already exist, for example when the process
+		// restarts, in which case the hard limit applies to it as well
+		if !forceNewFile && rl.hardLimit() {
+			if fi, err := os.Stat(filename); err == nil && rl.sizeDueNolock(fi.Size(), incoming) {
+				forceNewFile = true
+			}
+		}
 	} else {
 		if !useGenerationalNames && !sizeRotation {
 			// nothing to do
@@ -148,31 +455,44 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 		forceNewFile = true
 		generation++
 	}
+
+	if rl.lockFn != "" {
+		// Make sure only one process rotates at a time
+		if err := rl.lockProcessNolock(true); err != nil {
+			return nil, errors.Wrap(err, `failed to acquire process lock`)
+		}
+		defer rl.unlockProcessNolock()
+
+		st, err := rl.readSharedStateNolock()
+		if err != nil {
+			return nil, err
+		}
+
+		// Another process may have already rotated the logs, in
+		// which case we follow it instead
+		if rl.shouldFollowNolock(st, baseFn, incoming) {
+			return rl.followNolock(st)
+		}
+	}
+
+	if previousFn != "" {
+		defer rl.instrumentRotationNolock(time.Now(), previousFn, &err)
+	}
+
 	if forceNewFile {
 		// A new file has been requested. Instead of just using the
 		// regular strftime pattern, we create a new file name using
 		// generational names such as "foo.1", "foo.2", "foo.3", etc
-		var name string
-		for {
-			if generation == 0 {
-				name = filename
-			} else {
-				name = fmt.Sprintf("%s.%d", filename, generation)
-			}
-			if _, err := os.Stat(name); err != nil {
-				filename = name
-
-				break
-			}
-			generation++
-		}
+		filename, generation = rl.nextAvailableFn(filename, generation)
 	}
 
-	fh, err := fileutil.CreateFile(filename)
+	fh, err := rl.createFileNolock(filename)
 	if err != nil {
-		return nil, errors.Wrapf(err, `failed to create a new file %v`, filename)
+		return nil, err
 	}
 
+	headerSize, headerErr := rl.writeHeaderNolock(fh, filename, previousFn)
+
 	if err := rl.rotateNolock(filename); err != nil {
 		err = errors.Wrap(err, "failed to rotate")
 		if bailOnRotateFail {
@@ -192,22 +512,292 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 	}
 
-	rl.outFh.Close()
+	footerErr := rl.writeFooterNolock(rl.outFh, previousFn, filename)
+
+	if err := rl.closeFileNolock(rl.outFh); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
 	rl.outFh = fh
 	rl.curBaseFn = baseFn
 	rl.curFn = filename
 	rl.generation = generation
+	rl.startFileNolock(filename, headerSize)
 
-	if h := rl.eventHandler; h != nil {
-		go h.Handle(&FileRotatedEvent{
-			prev:    previousFn,
-			current: filename,
-		})
+	if rl.lockFn != "" {
+		if err := rl.writeSharedStateNolock(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		}
+	}
+
+	rl.notifyRotatedNolock(previousFn, filename)
+
+	if err := rl.headerFooterErrNolock(bailOnRotateFail, headerErr, footerErr); err != nil {
+		return nil, err
+	}
+
+	return fh, nil
+}
+
+// headerFooterErrNolock handles the errors that occurred while writing
+// the header and footer upon rotation. They are returned when explicitly
+// needed (as specified by bailOnRotateFail), as parsers may depend on
+// them, and otherwise only reported
+func (rl *RotateLogs) headerFooterErrNolock(bailOnRotateFail bool, errs ...error) error {
+	var merr multiError
+	for _, err := range errs {
+		if err != nil {
+			merr = append(merr, err)
+		}
+	}
+
+	if len(merr) == 0 {
+		return nil
+	}
+	if bailOnRotateFail {
+		return merr
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", merr.Error())
+	return nil
+}
+
+// sizeDueNolock reports whether a file of the given size must be rotated
+// before incoming more bytes are written to it
+func (rl *RotateLogs) sizeDueNolock(size int64, incoming int) bool {
+	if rl.rotationSize <= 0 {
+		return false
+	}
+
+	if rl.hardLimit() {
+		// A file holding nothing but its header is never rotated, as
+		// the next one would not have more room. Room is reserved for
+		// the footer, if any
+		return size > rl.headerSize && size+int64(incoming) > rl.rotationSize-rl.footerSize
+	}
+
+	return rl.rotationSize <= size
+}
+
+// getStableWriterNolock is the counterpart of getWriterNolock for
+// rotation strategies where logs are always written to rl.stableFn.
+// Instead of switching to a new file name, the contents of the current
+// file are archived under the file name generated from the pattern
+// (see archiveNolock), and writing continues under the same name.
+//
+// must be locked during this operation
+func (rl *RotateLogs) getStableWriterNolock(bailOnRotateFail, useGenerationalNames bool, incoming int) (_ io.Writer, err error) {
+	baseFn := fileutil.GenerateFn(rl.pattern, rl.clock, rl.rotationTime)
+
+	fi, statErr := os.Stat(rl.stableFn)
+	size, sizeErr := rl.curSizeNolock(rl.stableFn)
+	sizeRotation := sizeErr == nil && rl.sizeDueNolock(size, incoming)
+
+	// archiveFn is the base file name that the current file should
+	// be moved to. It is computed based on the period the current
+	// file was written in, not the period we are in now.
+	var archiveFn string
+	if rl.outFh == nil {
+		// This is the first write after calling New(). If a file
+		// from a previous run exists, it is archived when it belongs
+		// to a different period, or when a new file is requested
+		if statErr == nil && fi.Size() > 0 {
+			modTime := fi.ModTime().In(rl.clock.Now().Location())
+			prevBaseFn := fileutil.GenerateFn(rl.pattern, clockFn(func() time.Time { return modTime }), rl.rotationTime)
+			if prevBaseFn != baseFn || rl.forceNewFile || sizeRotation {
+				archiveFn = prevBaseFn
+			}
+		}
+	} else {
+		if baseFn == rl.curBaseFn && !useGenerationalNames && !sizeRotation {
+			// nothing to do
+			return rl.outFh, nil
+		}
+		archiveFn = rl.curBaseFn
+	}
+
+	var archivedFn string
+	var headerErr, footerErr error
+	if archiveFn != "" {
+		// The archive name may clash with files that were archived
+		// earlier in the same period, in which case generational
+		// names such as "foo.1", "foo.2", "foo.3" are used
+		archivedFn, _ = rl.nextAvailableFn(archiveFn, 0)
+		defer rl.instrumentRotationNolock(time.Now(), archivedFn, &err)
+		footerErr = rl.writeFooterNolock(rl.outFh, archivedFn, rl.stableFn)
+		if err := rl.archiveNolock(archivedFn); err != nil {
+			return nil, err
+		}
+	}
+
+	// The file is new, or has just been truncated
+	started := rl.outFh == nil || archivedFn != ""
+	if rl.outFh == nil {
+		fh, err := rl.createFileNolock(rl.stableFn)
+		if err != nil {
+			return nil, err
+		}
+		rl.outFh = fh
+	}
+
+	if started {
+		var headerSize int64
+		headerSize, headerErr = rl.writeHeaderNolock(rl.outFh, rl.stableFn, archivedFn)
+		rl.startFileNolock(rl.stableFn, headerSize)
+	}
+
+	rl.curBaseFn = baseFn
+	rl.curFn = rl.stableFn
+
+	if err := rl.rotateNolock(rl.stableFn); err != nil {
+		err = errors.Wrap(err, "failed to rotate")
+		if bailOnRotateFail {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+
+	if archivedFn != "" {
+		rl.notifyRotatedNolock(archivedFn, rl.stableFn)
+	}
+
+	if err := rl.headerFooterErrNolock(bailOnRotateFail, headerErr, footerErr); err != nil {
+		return nil, err
+	}
+
+	return rl.outFh, nil
+}
+
+// archiveNolock moves the contents of rl.stableFn to archivedFn.
+//
+// For strategyRename the file is closed and renamed, and a new file
+// is created by the caller. For strategyCopyTruncate the file is copied
+// and then truncated in place, so that the file handle (and those held
+// by other processes) remains valid.
+func (rl *RotateLogs) archiveNolock(archivedFn string) error {
+	switch rl.strategy {
+	case strategyRename:
+		if err := rl.closeFileNolock(rl.outFh); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		}
+		rl.outFh = nil
+
+		if err := fileutil.MoveFile(rl.stableFn, archivedFn, rl.dirMode); err != nil {
+			return errors.Wrapf(err, `failed to move %s to %s`, rl.stableFn, archivedFn)
+		}
+
+		if err := rl.syncDirNolock(archivedFn); err != nil {
+			return err
+		}
+	case strategyCopyTruncate:
+		src, err := os.Open(rl.stableFn)
+		if err != nil {
+			return errors.Wrapf(err, `failed to open %s`, rl.stableFn)
+		}
+		defer src.Close()
+
+		dst, err := rl.createFileNolock(archivedFn)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close()
+
+			return errors.Wrapf(err, `failed to copy %s to %s`, rl.stableFn, archivedFn)
+		}
+
+		if err := rl.closeFileNolock(dst); err != nil {
+			return errors.Wrapf(err, `failed to close %s`, archivedFn)
+		}
+
+		if err := os.Truncate(rl.stableFn, 0); err != nil {
+			return errors.Wrapf(err, `failed to truncate %s`, rl.stableFn)
+		}
+		// the size tracked in memory is not valid anymore
+		rl.sizeFh = nil
+	}
+
+	return nil
+}
+
+// createFileNolock opens the given file for appending, creating it
+// along with its parent directories as necessary
+func (rl *RotateLogs) createFileNolock(filename string) (*os.File, error) {
+	fh, err := fileutil.CreateFile(filename, rl.fileMode, rl.dirMode)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to create a new file %v`, filename)
+	}
+
+	if owner := rl.owner; owner != nil {
+		if err := fileutil.Chown(fh, owner.uid, owner.gid); err != nil {
+			fh.Close()
+
+			return nil, err
+		}
+	}
+
+	if err := rl.syncDirNolock(filename); err != nil {
+		fh.Close()
+
+		return nil, err
 	}
 
 	return fh, nil
 }
 
+// nextAvailableFn returns the first file name that does not exist yet,
+// starting from the given generation. Generation 0 denotes the file name
+// itself, and subsequent generations are named by rl.genFormatter
+func (rl *RotateLogs) nextAvailableFn(filename string, generation int) (string, int) {
+	for {
+		name := filename
+		if generation > 0 {
+			name = rl.genFormatter.FormatGeneration(filename, generation)
+		}
+		if _, err := os.Stat(name); err != nil {
+			return name, generation
+		}
+		generation++
+	}
+}
+
+// instrumentRotationNolock reports a rotation that started at start to
+// the Instrumentation, if any. It is meant to be deferred, so that errp
+// points to the error eventually returned
+func (rl *RotateLogs) instrumentRotationNolock(start time.Time, previousFn string, errp *error) {
+	if rl.instrument == nil {
+		return
+	}
+
+	info := RotateInfo{
+		Start:        start,
+		Duration:     time.Since(start),
+		PreviousFile: previousFn,
+		Err:          *errp,
+	}
+	if info.Err == nil {
+		info.CurrentFile = rl.curFn
+	}
+	rl.instrument.OnRotate(info)
+}
+
+func (rl *RotateLogs) notifyRotatedNolock(previousFn, currentFn string) {
+	if previousFn != "" {
+		rl.stats.recordRotation(rl.clock.Now())
+	}
+
+	if h := rl.eventHandler; h != nil {
+		ev := &FileRotatedEvent{
+			prev:    previousFn,
+			current: currentFn,
+		}
+		rl.wg.Add(1)
+		go func() {
+			defer rl.wg.Done()
+			h.Handle(ev)
+		}()
+	}
+}
+
 // CurrentFileName returns the current file name that
 // the RotateLogs object is writing to
 func (rl *RotateLogs) CurrentFileName() string {
@@ -248,14 +838,90 @@ func (g *cleanupGuard) Run() {
 func (rl *RotateLogs) Rotate() error {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
-	_, err := rl.getWriterNolock(true, true)
+
+	if rl.closed {
+		return ErrClosed
+	}
+
+	_, err := rl.getWriterNolock(true, true, 0)
 
 	return err
 }
 
+// Reopen closes the current file and opens it again under the same
+// file name, creating it if necessary. Unlike Rotate, no new file name
+// is generated.
+//
+// This method can be used in conjunction with external log rotation
+// tools which move the file away and then notify the application
+func (rl *RotateLogs) Reopen() error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.closed {
+		return ErrClosed
+	}
+
+	if rl.outFh == nil {
+		// nothing has been opened yet. The file will be opened
+		// on the next write
+		return nil
+	}
+
+	return rl.reopenNolock()
+}
+
+// linkNolock points the symbolic link at rl.linkName to filename
+func (rl *RotateLogs) linkNolock(filename string) error {
+	tmpLinkName := filename + `_symlink`
+
+	// Change how the link name is generated based on where the
+	// target location is. if the location is directly underneath
+	// the main filename's parent directory, then we create a
+	// symlink with a relative path
+	linkDest := filename
+	linkDir := filepath.Dir(rl.linkName)
+
+	baseDir := filepath.Dir(filename)
+	if strings.Contains(rl.linkName, baseDir) {
+		tmp, err := filepath.Rel(linkDir, filename)
+		if err != nil {
+			return errors.Wrapf(err, `failed to evaluate relative path from %#v to %#v`, baseDir, rl.linkName)
+		}
+
+		linkDest = tmp
+	}
+
+	if err := os.Symlink(linkDest, tmpLinkName); err != nil {
+		return errors.Wrap(err, `failed to create new symlink`)
+	}
+
+	// the directory where rl.linkName should be created must exist
+	_, err := os.Stat(linkDir)
+	if err != nil { // Assume err != nil means the directory doesn't exist
+		if err := os.MkdirAll(linkDir, rl.dirMode); err != nil {
+			os.Remove(tmpLinkName)
+			return errors.Wrapf(err, `failed to create directory %s`, linkDir)
+		}
+	}
+
+	if err := os.Rename(tmpLinkName, rl.linkName); err != nil {
+		os.Remove(tmpLinkName)
+		return errors.Wrap(err, `failed to rename new symlink`)
+	}
+
+	return rl.syncDirNolock(rl.linkName)
+}
+
 func (rl *RotateLogs) rotateNolock(filename string) error {
 	lockfn := filename + `_lock`
-	fh, err := os.OpenFile(lockfn, os.O_CREATE|os.O_EXCL, 0644)
+	if rl.lockFn != "" {
+		// We are holding the process lock, so nobody else can be
+		// rotating right now: if a lock file exists, it must have
+		// been left behind by a process that crashed
+		os.Remove(lockfn)
+	}
+	fh, err := rl.createLockFile(lockfn)
 	if err != nil {
 		// Can't lock, just return
 		return err
@@ -269,39 +935,8 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 	defer guard.Run()
 
 	if rl.linkName != "" {
-		tmpLinkName := filename + `_symlink`
-
-		// Change how the link name is generated based on where the
-		// target location is. if the location is directly underneath
-		// the main filename's parent directory, then we create a
-		// symlink with a relative path
-		linkDest := filename
-		linkDir := filepath.Dir(rl.linkName)
-
-		baseDir := filepath.Dir(filename)
-		if strings.Contains(rl.linkName, baseDir) {
-			tmp, err := filepath.Rel(linkDir, filename)
-			if err != nil {
-				return errors.Wrapf(err, `failed to evaluate relative path from %#v to %#v`, baseDir, rl.linkName)
-			}
-
-			linkDest = tmp
-		}
-
-		if err := os.Symlink(linkDest, tmpLinkName); err != nil {
-			return errors.Wrap(err, `failed to create new symlink`)
-		}
-
-		// the directory where rl.linkName should be created must exist
-		_, err := os.Stat(linkDir)
-		if err != nil { // Assume err != nil means the directory doesn't exist
-			if err := os.MkdirAll(linkDir, 0755); err != nil {
-				return errors.Wrapf(err, `failed to create directory %s`, linkDir)
-			}
-		}
-
-		if err := os.Rename(tmpLinkName, rl.linkName); err != nil {
-			return errors.Wrap(err, `failed to rename new symlink`)
+		if err := rl.linkNolock(filename); err != nil {
+			return err
 		}
 	}
 
@@ -309,7 +944,7 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 		return errors.New("panic: maxAge and rotationCount are both set")
 	}
 
-	matches, err := filepath.Glob(rl.globPattern)
+	matches, err := rl.globNolock()
 	if err != nil {
 		return err
 	}
@@ -318,12 +953,19 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 
 	// the linter tells me to pre allocate this...
 	toUnlink := make([]string, 0, len(matches))
+	modTimes := make(map[string]time.Time, len(matches))
 	for _, path := range matches {
 		// Ignore lock files
 		if strings.HasSuffix(path, "_lock") || strings.HasSuffix(path, "_symlink") {
 			continue
 		}
 
+		// Ignore the file that we are always writing to, and the one
+		// that we are about to write to
+		if path == rl.stableFn || path == filename {
+			continue
+		}
+
 		fi, err := os.Stat(path)
 		if err != nil {
 			continue
@@ -342,15 +984,24 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 			continue
 		}
 		toUnlink = append(toUnlink, path)
+		modTimes[path] = fi.ModTime()
 	}
 
 	if rl.rotationCount > 0 {
-		// Only delete if we have more than rotationCount
-		if rl.rotationCount >= uint(len(toUnlink)) {
+		// The file that we are about to write to counts as one of the
+		// files to keep
+		keep := int(rl.rotationCount) - 1
+		if keep >= len(toUnlink) {
 			return nil
 		}
 
-		toUnlink = toUnlink[:len(toUnlink)-int(rl.rotationCount)]
+		// Keep the most recently modified files. File names can't be
+		// relied upon, as generational names do not necessarily sort
+		// in the order they were created
+		sort.SliceStable(toUnlink, func(i, j int) bool {
+			return modTimes[toUnlink[i]].Before(modTimes[toUnlink[j]])
+		})
+		toUnlink = toUnlink[:len(toUnlink)-keep]
 	}
 
 	if len(toUnlink) <= 0 {
@@ -358,29 +1009,125 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 	}
 
 	guard.Enable()
-	go func() {
+	rl.wg.Add(1)
+	job := func() {
+		defer rl.wg.Done()
+		rl.purge(toUnlink)
+	}
+	if rl.purger != nil {
+		rl.purger.submit(job)
+	} else {
 		// unlink files on a separate goroutine
-		for _, path := range toUnlink {
-			os.Remove(path)
-		}
-	}()
+		go job()
+	}
 
 	return nil
 }
 
+// purge removes the given files, and reports the outcome
+func (rl *RotateLogs) purge(toUnlink []string) {
+	info := PurgeInfo{
+		Start: time.Now(),
+		Files: toUnlink,
+	}
+	for _, path := range toUnlink {
+		err := os.Remove(path)
+		rl.stats.recordPurge(err)
+		if err != nil && !os.IsNotExist(err) {
+			info.Failures++
+			if info.Err == nil {
+				info.Err = err
+			}
+		}
+	}
+	if rl.instrument != nil {
+		info.Duration = time.Since(info.Start)
+		rl.instrument.OnPurge(info)
+	}
+}
+
+// globNolock returns the list of files that match the pattern, including
+// files with generational names
+func (rl *RotateLogs) globNolock() ([]string, error) {
+	matches, err := filepath.Glob(rl.globPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	genGlobPattern := generationGlob(rl.genFormatter, rl.globPattern)
+	if genGlobPattern == "" || genGlobPattern == rl.globPattern {
+		return matches, nil
+	}
+
+	genMatches, err := filepath.Glob(genGlobPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(matches))
+	for _, path := range matches {
+		seen[path] = struct{}{}
+	}
+	for _, path := range genMatches {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		matches = append(matches, path)
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
 // Close satisfies the io.Closer interface. You must
 // call this method if you performed any writes to
 // the object.
+//
+// Once closed, the object cannot be written to anymore, and
+// most operations return ErrClosed. Calling Close more than
+// once is a no-op.
 func (rl *RotateLogs) Close() error {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
+	if err := rl.flushPartialNolock(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+	rl.markClosedNolock()
+	rl.stopSyncNolock()
+	rl.stopMirrorsNolock()
+
+	if rl.lockFh != nil {
+		rl.lockFh.Close()
+		rl.lockFh = nil
+	}
+
 	if rl.outFh == nil {
 		return nil
 	}
 
-	rl.outFh.Close()
+	err := rl.closeFileNolock(rl.outFh)
 	rl.outFh = nil
 
-	return nil
+	return err
+}
+
+// markClosedNolock marks the object as closed, and stops the goroutines
+// started by RotateOnSignal and ReopenOnSignal
+func (rl *RotateLogs) markClosedNolock() {
+	if !rl.closed && rl.done != nil {
+		close(rl.done)
+	}
+	rl.closed = true
+}
+
+// stopSyncNolock stops the background goroutine started for SyncInterval
+//
+// must be locked during this operation
+func (rl *RotateLogs) stopSyncNolock() {
+	if rl.syncStop != nil {
+		close(rl.syncStop)
+		rl.syncStop = nil
+	}
 }
@@ -1,13 +1,17 @@
 package rotatelogs_test
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -580,3 +584,1525 @@ func TestForceNewFile(t *testing.T) {
 		}
 	})
 }
+
+func TestRenameOnRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-rename-on-rotate")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dummyTime := time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(dummyTime)
+	stableFn := filepath.Join(dir, "app.log")
+
+	rotated := make(chan string, 10)
+	rl, err := rotatelogs.New(
+		filepath.Join(dir, "app.%Y-%m-%dT%H.log"),
+		rotatelogs.WithClock(clock),
+		rotatelogs.WithRotationTime(time.Hour),
+		rotatelogs.WithRenameOnRotate(stableFn),
+		rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+			rotated <- e.(*rotatelogs.FileRotatedEvent).PreviousFile()
+		})),
+	)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+	defer rl.Close()
+
+	rl.Write([]byte("first"))
+	if !assert.Equal(t, stableFn, rl.CurrentFileName(), "logs should be written to the stable file name") {
+		return
+	}
+
+	clock.Advance(time.Hour)
+	rl.Write([]byte("second"))
+
+	archivedFn := filepath.Join(dir, "app.2024-05-01T00.log")
+	content, err := ioutil.ReadFile(archivedFn)
+	if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", archivedFn) {
+		return
+	}
+	if !assert.Equal(t, "first", string(content), "archived file should contain data from the previous period") {
+		return
+	}
+
+	content, err = ioutil.ReadFile(stableFn)
+	if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", stableFn) {
+		return
+	}
+	if !assert.Equal(t, "second", string(content), "stable file should contain data from the current period") {
+		return
+	}
+
+	select {
+	case fn := <-rotated:
+		if !assert.Equal(t, archivedFn, fn, "event should report the archived file name") {
+			return
+		}
+	case <-time.After(time.Second):
+		assert.Fail(t, "timed out waiting for rotation event")
+
+		return
+	}
+
+	// Forcefully rotating twice within the same period should yield
+	// generational names
+	for _, expected := range []string{"app.2024-05-01T01.log", "app.2024-05-01T01.log.1"} {
+		if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+			return
+		}
+		assert.FileExists(t, filepath.Join(dir, expected), "file does not exist %s", expected)
+	}
+	assert.FileExists(t, stableFn, "file does not exist %s", stableFn)
+}
+
+func TestGenerationFormat(t *testing.T) {
+	t.Run("Presets", func(t *testing.T) {
+		assert.Equal(t, "app.log.1", rotatelogs.GenerationSuffix.FormatGeneration("app.log", 1))
+		assert.Equal(t, "app.1.log", rotatelogs.GenerationBeforeExt.FormatGeneration("app.log", 1))
+		assert.Equal(t, "app.1", rotatelogs.GenerationBeforeExt.FormatGeneration("app", 1))
+		assert.Equal(t, "app.log.001", rotatelogs.GenerationZeroPadded(3).FormatGeneration("app.log", 1))
+	})
+
+	dir, err := ioutil.TempDir("", "file-rotatelogs-generation-format")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("Before extension", func(t *testing.T) {
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "before-ext.%Y%m%d.log"),
+			rotatelogs.WithClock(clock),
+			rotatelogs.WithGenerationFormat(rotatelogs.GenerationBeforeExt),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("Hello, World!"))
+		for i := 1; i <= 3; i++ {
+			if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+				return
+			}
+			expected := filepath.Join(dir, fmt.Sprintf("before-ext.20240501.%d.log", i))
+			if !assert.Equal(t, expected, rl.CurrentFileName(), "file names should match") {
+				return
+			}
+		}
+	})
+
+	t.Run("Generational names are purged", func(t *testing.T) {
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "purge.%Y%m%d.log"),
+			rotatelogs.WithClock(clock),
+			rotatelogs.WithMaxAge(-1),
+			rotatelogs.WithRotationCount(2),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("Hello, World!"))
+		for i := 0; i < 4; i++ {
+			if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+				return
+			}
+		}
+		time.Sleep(time.Second)
+
+		files, _ := filepath.Glob(filepath.Join(dir, "purge.*"))
+		assert.Equal(t, 2, len(files), "Only 2 files should be kept: %v", files)
+	})
+
+	t.Run("RotationCount keeps the newest files", func(t *testing.T) {
+		presets := []struct {
+			Name      string
+			Format    rotatelogs.GenerationFormatter
+			Generated string // generation 1 of the first day
+		}{
+			{Name: "suffix", Format: rotatelogs.GenerationSuffix, Generated: "log.20240501.1"},
+			{Name: "beforeext", Format: rotatelogs.GenerationBeforeExt, Generated: "log.1.20240501"},
+			{Name: "zeropadded", Format: rotatelogs.GenerationZeroPadded(3), Generated: "log.20240501.001"},
+		}
+
+		for _, preset := range presets {
+			preset := preset
+			t.Run(preset.Name, func(t *testing.T) {
+				subdir := filepath.Join(dir, "count-"+preset.Name)
+				clock := clockwork.NewFakeClockAt(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+				rl, err := rotatelogs.New(
+					filepath.Join(subdir, "log.%Y%m%d"),
+					rotatelogs.WithClock(clock),
+					rotatelogs.WithMaxAge(-1),
+					rotatelogs.WithRotationCount(2),
+					rotatelogs.WithGenerationFormat(preset.Format),
+				)
+				if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+					return
+				}
+
+				rl.Write([]byte("Hello, World!"))
+				time.Sleep(10 * time.Millisecond)
+				if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+				clock.Advance(24 * time.Hour)
+				if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+					return
+				}
+				rl.Write([]byte("Hello, World!"))
+
+				if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+					return
+				}
+
+				files, _ := filepath.Glob(filepath.Join(subdir, "log.*"))
+				expected := []string{
+					filepath.Join(subdir, preset.Generated),
+					filepath.Join(subdir, "log.20240502"),
+				}
+				assert.ElementsMatch(t, expected, files, "the two newest files should be kept")
+			})
+		}
+	})
+}
+
+func TestFilePermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-permissions")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	options := []rotatelogs.Option{
+		rotatelogs.WithFileMode(0600),
+		rotatelogs.WithDirMode(0700),
+	}
+	if runtime.GOOS == "linux" {
+		options = append(options, rotatelogs.WithOwner(os.Getuid(), os.Getgid()))
+	} else {
+		_, err := rotatelogs.New(filepath.Join(dir, "owner%Y%m%d"), rotatelogs.WithOwner(os.Getuid(), os.Getgid()))
+		assert.True(t, errors.Is(err, rotatelogs.ErrUnsupported), "rotatelogs.New should fail with ErrUnsupported")
+	}
+
+	rl, err := rotatelogs.New(filepath.Join(dir, "nested", "log%Y%m%d"), options...)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+	defer rl.Close()
+
+	if _, err := rl.Write([]byte("Hello, World!")); !assert.NoError(t, err, "rl.Write should succeed") {
+		return
+	}
+
+	fi, err := os.Stat(rl.CurrentFileName())
+	if !assert.NoError(t, err, "os.Stat should succeed") {
+		return
+	}
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "file mode should match")
+
+	fi, err = os.Stat(filepath.Join(dir, "nested"))
+	if !assert.NoError(t, err, "os.Stat should succeed") {
+		return
+	}
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm(), "directory mode should match")
+}
+
+func TestCopyTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-copy-truncate")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC))
+	stableFn := filepath.Join(dir, "app.log")
+
+	t.Run("Cannot be used with RenameOnRotate", func(t *testing.T) {
+		_, err := rotatelogs.New(
+			filepath.Join(dir, "app.%Y-%m-%dT%H.log"),
+			rotatelogs.WithRenameOnRotate(stableFn),
+			rotatelogs.WithCopyTruncate(stableFn),
+		)
+		assert.Error(t, err, `rotatelogs.New should fail`)
+	})
+
+	t.Run("Copy and truncate in place", func(t *testing.T) {
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "app.%Y-%m-%dT%H.log"),
+			rotatelogs.WithClock(clock),
+			rotatelogs.WithRotationTime(time.Hour),
+			rotatelogs.WithCopyTruncate(stableFn),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("first"))
+		before, err := os.Stat(stableFn)
+		if !assert.NoError(t, err, "os.Stat should succeed") {
+			return
+		}
+
+		clock.Advance(time.Hour)
+		rl.Write([]byte("second"))
+
+		archivedFn := filepath.Join(dir, "app.2024-05-01T00.log")
+		content, err := ioutil.ReadFile(archivedFn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", archivedFn) {
+			return
+		}
+		if !assert.Equal(t, "first", string(content), "archived file should contain data from the previous period") {
+			return
+		}
+
+		content, err = ioutil.ReadFile(stableFn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", stableFn) {
+			return
+		}
+		if !assert.Equal(t, "second", string(content), "file should have been truncated before writing") {
+			return
+		}
+
+		after, err := os.Stat(stableFn)
+		if !assert.NoError(t, err, "os.Stat should succeed") {
+			return
+		}
+		assert.True(t, os.SameFile(before, after), "file should have been truncated in place")
+	})
+}
+
+func TestFileWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-file-watch")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	rl, err := rotatelogs.New(
+		filepath.Join(dir, "log%Y%m%d"),
+		rotatelogs.WithFileWatch(0),
+	)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+	defer rl.Close()
+
+	rl.Write([]byte("first"))
+	fn := rl.CurrentFileName()
+
+	t.Run("Removed file is recreated", func(t *testing.T) {
+		if !assert.NoError(t, os.Remove(fn), "os.Remove should succeed") {
+			return
+		}
+
+		rl.Write([]byte("second"))
+		content, err := ioutil.ReadFile(fn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", fn) {
+			return
+		}
+		assert.Equal(t, "second", string(content), "file should have been recreated")
+	})
+
+	t.Run("Renamed file is reopened", func(t *testing.T) {
+		movedFn := fn + ".moved"
+		if !assert.NoError(t, os.Rename(fn, movedFn), "os.Rename should succeed") {
+			return
+		}
+
+		rl.Write([]byte("third"))
+		content, err := ioutil.ReadFile(fn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", fn) {
+			return
+		}
+		assert.Equal(t, "third", string(content), "file should have been recreated")
+
+		content, err = ioutil.ReadFile(movedFn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", movedFn) {
+			return
+		}
+		assert.Equal(t, "second", string(content), "renamed file should not be written to")
+	})
+
+	t.Run("Interval does not depend on the clock", func(t *testing.T) {
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "interval%Y%m%d"),
+			rotatelogs.WithClock(clockwork.NewFakeClock()),
+			rotatelogs.WithFileWatch(10*time.Millisecond),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("first"))
+		fn := rl.CurrentFileName()
+		if !assert.NoError(t, os.Remove(fn), "os.Remove should succeed") {
+			return
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		rl.Write([]byte("second"))
+		content, err := ioutil.ReadFile(fn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", fn) {
+			return
+		}
+		assert.Equal(t, "second", string(content), "file should have been recreated")
+	})
+}
+
+func TestSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals is not supported on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "file-rotatelogs-signal")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	proc, err := os.FindProcess(os.Getpid())
+	if !assert.NoError(t, err, "os.FindProcess should succeed") {
+		return
+	}
+
+	t.Run("Reopen on signal", func(t *testing.T) {
+		rl, err := rotatelogs.New(filepath.Join(dir, "reopen.log"))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rl.ReopenOnSignal(ctx)
+
+		rl.Write([]byte("first"))
+		fn := rl.CurrentFileName()
+		if !assert.NoError(t, os.Rename(fn, fn+".1"), "os.Rename should succeed") {
+			return
+		}
+
+		if !assert.NoError(t, proc.Signal(syscall.SIGHUP), "proc.Signal should succeed") {
+			return
+		}
+
+		// wait for the file to be reopened
+		for i := 0; i < 100; i++ {
+			if _, err := os.Stat(fn); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		rl.Write([]byte("second"))
+		assert.Equal(t, fn, rl.CurrentFileName(), "file name should not change")
+		content, err := ioutil.ReadFile(fn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", fn) {
+			return
+		}
+		assert.Equal(t, "second", string(content), "file should have been reopened")
+	})
+
+	t.Run("Rotate on signal", func(t *testing.T) {
+		rotated := make(chan struct{}, 1)
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "rotate.log"),
+			rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+				if e.(*rotatelogs.FileRotatedEvent).PreviousFile() != "" {
+					rotated <- struct{}{}
+				}
+			})),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rl.RotateOnSignal(ctx, syscall.SIGHUP)
+
+		rl.Write([]byte("first"))
+		if !assert.NoError(t, proc.Signal(syscall.SIGHUP), "proc.Signal should succeed") {
+			return
+		}
+
+		select {
+		case <-rotated:
+		case <-time.After(time.Second):
+			assert.Fail(t, "timed out waiting for rotation")
+
+			return
+		}
+		assert.Equal(t, filepath.Join(dir, "rotate.log.1"), rl.CurrentFileName(), "file should have been rotated")
+	})
+
+	t.Run("Stop on close", func(t *testing.T) {
+		rl, err := rotatelogs.New(filepath.Join(dir, "close.log"))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		before := runtime.NumGoroutine()
+		rl.RotateOnSignal(context.Background())
+		rl.Close()
+
+		// wait for the goroutine to notice, without sending a signal
+		for i := 0; i < 100; i++ {
+			if runtime.NumGoroutine() <= before {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.True(t, runtime.NumGoroutine() <= before, "goroutine should stop when closed")
+	})
+}
+
+func TestProcessLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-process-lock")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	switch runtime.GOOS {
+	case "linux", "darwin", "dragonfly", "freebsd", "netbsd", "openbsd":
+	default:
+		_, err := rotatelogs.New(filepath.Join(dir, "app.log"), rotatelogs.WithProcessLock(filepath.Join(dir, "app.lock")))
+		assert.True(t, errors.Is(err, rotatelogs.ErrUnsupported), "rotatelogs.New should fail with ErrUnsupported")
+		return
+	}
+
+	// Each RotateLogs object holds its own lock file descriptor, so
+	// two objects in the same process behave like two processes
+	var writers []*rotatelogs.RotateLogs
+	for i := 0; i < 2; i++ {
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "app.log"),
+			rotatelogs.WithRotationSize(10),
+			rotatelogs.WithProcessLock(filepath.Join(dir, "app.lock")),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+		writers = append(writers, rl)
+	}
+	a, b := writers[0], writers[1]
+
+	a.Write([]byte("01234"))
+	b.Write([]byte("56789"))
+	if !assert.Equal(t, a.CurrentFileName(), b.CurrentFileName(), "both writers should write to the same file") {
+		return
+	}
+
+	// A lock file left behind by a crashed process should not
+	// prevent the rotation from completing
+	staleLockFn := filepath.Join(dir, "app.log.1_lock")
+	if !assert.NoError(t, ioutil.WriteFile(staleLockFn, nil, 0644), "ioutil.WriteFile should succeed") {
+		return
+	}
+
+	// The file is full: a rotates, and b should follow
+	a.Write([]byte("a"))
+	b.Write([]byte("b"))
+
+	expected := filepath.Join(dir, "app.log.1")
+	assert.Equal(t, expected, a.CurrentFileName(), "a should have rotated")
+	assert.Equal(t, expected, b.CurrentFileName(), "b should have followed a")
+	assert.Equal(t, 1, b.Stats().CurrentGeneration, "b should have taken over the generation of a")
+	_, err = os.Stat(filepath.Join(dir, "app.log.2"))
+	assert.True(t, os.IsNotExist(err), "b should not have rotated on its own")
+	_, err = os.Stat(staleLockFn)
+	assert.True(t, os.IsNotExist(err), "stale lock file should have been removed")
+
+	content, err := ioutil.ReadFile(expected)
+	if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", expected) {
+		return
+	}
+	assert.Equal(t, "ab", string(content), "both writers should write to the new file")
+}
+
+func TestStaleLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-stale-lock")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		Name          string
+		Content       string
+		Stale         bool
+		NeedsPidCheck bool
+	}{
+		{
+			Name:    "Lock held by a live process",
+			Content: fmt.Sprintf("%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339Nano)),
+		},
+		{
+			Name:    "Lock older than the timeout",
+			Content: fmt.Sprintf("%d\n%s\n", os.Getpid(), time.Now().Add(-time.Hour).Format(time.RFC3339Nano)),
+			Stale:   true,
+		},
+		{
+			Name:          "Lock held by a dead process",
+			Content:       fmt.Sprintf("%d\n%s\n", 1<<30, time.Now().Format(time.RFC3339Nano)),
+			Stale:         true,
+			NeedsPidCheck: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		i := i
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			if tc.NeedsPidCheck && runtime.GOOS == "windows" {
+				t.Skip("detecting dead processes is not supported on windows")
+			}
+
+			baseFn := filepath.Join(dir, fmt.Sprintf("stale%d.log", i))
+			rl, err := rotatelogs.New(baseFn, rotatelogs.WithStaleLockTimeout(time.Minute))
+			if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+				return
+			}
+			defer rl.Close()
+
+			rl.Write([]byte("Hello, World!"))
+
+			// Rotate() creates baseFn.1, which is guarded by baseFn.1_lock
+			lockFn := baseFn + ".1_lock"
+			if !assert.NoError(t, ioutil.WriteFile(lockFn, []byte(tc.Content), 0644), "ioutil.WriteFile should succeed") {
+				return
+			}
+
+			err = rl.Rotate()
+			if tc.Stale {
+				assert.NoError(t, err, "rl.Rotate should succeed")
+			} else {
+				assert.True(t, errors.Is(err, rotatelogs.ErrRotateLocked), "rl.Rotate should fail with ErrRotateLocked (got %v)", err)
+			}
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-shutdown")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	release := make(chan struct{})
+	handled := make(chan struct{})
+	rl, err := rotatelogs.New(
+		filepath.Join(dir, "log%Y%m%d"),
+		rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(_ rotatelogs.Event) {
+			<-release
+			close(handled)
+		})),
+	)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+
+	rl.Write([]byte("Hello, World!"))
+	fn := rl.CurrentFileName()
+
+	t.Run("Times out waiting for event handlers", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := rl.Shutdown(ctx)
+		if !assert.Error(t, err, "rl.Shutdown should fail") {
+			return
+		}
+		assert.Contains(t, err.Error(), context.DeadlineExceeded.Error(), "error should report the deadline")
+	})
+
+	t.Run("Waits for event handlers", func(t *testing.T) {
+		close(release)
+		if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+			return
+		}
+
+		select {
+		case <-handled:
+		default:
+			assert.Fail(t, "event handler should have completed")
+		}
+
+		content, err := ioutil.ReadFile(fn)
+		if !assert.NoError(t, err, "ioutil.ReadFile %s should succeed", fn) {
+			return
+		}
+		assert.Equal(t, "Hello, World!", string(content), "file content should match")
+	})
+}
+
+func TestSyncPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-sync-policy")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	policies := map[string]rotatelogs.SyncPolicy{
+		"never":       rotatelogs.SyncNever,
+		"every-write": rotatelogs.SyncEveryWrite,
+		"on-rotate":   rotatelogs.SyncOnRotate,
+		"interval":    rotatelogs.SyncInterval(10 * time.Millisecond),
+	}
+
+	for name, policy := range policies {
+		name := name
+		policy := policy
+		t.Run(name, func(t *testing.T) {
+			rl, err := rotatelogs.New(
+				filepath.Join(dir, name+".log"),
+				rotatelogs.WithLinkName(filepath.Join(dir, name+".current")),
+				rotatelogs.WithSyncPolicy(policy),
+			)
+			if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+				return
+			}
+			defer rl.Close()
+
+			for i := 0; i < 3; i++ {
+				if _, err := rl.Write([]byte("Hello, World!")); !assert.NoError(t, err, "rl.Write should succeed") {
+					return
+				}
+				if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+					return
+				}
+			}
+
+			rl.Write([]byte("Hello, World!"))
+			time.Sleep(50 * time.Millisecond)
+			if !assert.NoError(t, rl.Sync(), "rl.Sync should succeed") {
+				return
+			}
+			if !assert.NoError(t, rl.Close(), "rl.Close should succeed") {
+				return
+			}
+
+			content, err := ioutil.ReadFile(filepath.Join(dir, name+".log.3"))
+			if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+				return
+			}
+			assert.Equal(t, "Hello, World!", string(content), "file content should match")
+		})
+	}
+
+	t.Run("non-positive interval", func(t *testing.T) {
+		_, err := rotatelogs.New(
+			filepath.Join(dir, "zero.log"),
+			rotatelogs.WithSyncPolicy(rotatelogs.SyncInterval(0)),
+		)
+		assert.Error(t, err, `rotatelogs.New should fail`)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-lifecycle")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("Close before write", func(t *testing.T) {
+		pattern := filepath.Join(dir, "unused.log")
+		rl, err := rotatelogs.New(pattern)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		if !assert.NoError(t, rl.Close(), "rl.Close should succeed") {
+			return
+		}
+
+		_, err = rl.Write([]byte("Hello, World!"))
+		assert.Equal(t, rotatelogs.ErrClosed, err, "rl.Write should fail with ErrClosed")
+		_, err = os.Stat(pattern)
+		assert.True(t, os.IsNotExist(err), "no file should have been created")
+	})
+
+	t.Run("Operations after close", func(t *testing.T) {
+		pattern := filepath.Join(dir, "closed.log")
+		rl, err := rotatelogs.New(pattern)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		if _, err := rl.Write([]byte("Hello, World!")); !assert.NoError(t, err, "rl.Write should succeed") {
+			return
+		}
+		if !assert.NoError(t, rl.Close(), "rl.Close should succeed") {
+			return
+		}
+		if !assert.NoError(t, os.Remove(pattern), "os.Remove should succeed") {
+			return
+		}
+
+		_, err = rl.Write([]byte("Hello, World!"))
+		assert.Equal(t, rotatelogs.ErrClosed, err, "rl.Write should fail with ErrClosed")
+		assert.Equal(t, rotatelogs.ErrClosed, rl.Rotate(), "rl.Rotate should fail with ErrClosed")
+		assert.Equal(t, rotatelogs.ErrClosed, rl.Reopen(), "rl.Reopen should fail with ErrClosed")
+		assert.Equal(t, rotatelogs.ErrClosed, rl.Sync(), "rl.Sync should fail with ErrClosed")
+		assert.Equal(t, pattern, rl.CurrentFileName(), "file name should be retained")
+
+		_, err = os.Stat(pattern)
+		assert.True(t, os.IsNotExist(err), "file should not have been resurrected")
+	})
+
+	t.Run("Close is idempotent", func(t *testing.T) {
+		rl, err := rotatelogs.New(filepath.Join(dir, "idempotent.log"))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		rl.Write([]byte("Hello, World!"))
+		assert.NoError(t, rl.Close(), "first rl.Close should succeed")
+		assert.NoError(t, rl.Close(), "second rl.Close should succeed")
+		assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown after rl.Close should succeed")
+	})
+
+	t.Run("Write after shutdown", func(t *testing.T) {
+		rl, err := rotatelogs.New(filepath.Join(dir, "shutdown.log"))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		rl.Write([]byte("Hello, World!"))
+		if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+			return
+		}
+
+		_, err = rl.Write([]byte("Hello, World!"))
+		assert.Equal(t, rotatelogs.ErrClosed, err, "rl.Write should fail with ErrClosed")
+		assert.NoError(t, rl.Close(), "rl.Close after rl.Shutdown should succeed")
+	})
+}
+
+func TestErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-errors")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("ErrInvalidPattern", func(t *testing.T) {
+		_, err := rotatelogs.New(filepath.Join(dir, "log%Q"))
+		assert.True(t, errors.Is(err, rotatelogs.ErrInvalidPattern), "error should match ErrInvalidPattern (got %v)", err)
+	})
+
+	t.Run("ErrConflictingOptions", func(t *testing.T) {
+		_, err := rotatelogs.New(
+			filepath.Join(dir, "log%Y%m%d"),
+			rotatelogs.WithMaxAge(time.Hour),
+			rotatelogs.WithRotationCount(1),
+		)
+		assert.True(t, errors.Is(err, rotatelogs.ErrConflictingOptions), "error should match ErrConflictingOptions (got %v)", err)
+	})
+
+	t.Run("ErrRotateLocked", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "locked.log")
+		rl, err := rotatelogs.New(baseFn)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("Hello, World!"))
+		if !assert.NoError(t, ioutil.WriteFile(baseFn+".1_lock", nil, 0644), "ioutil.WriteFile should succeed") {
+			return
+		}
+
+		err = rl.Rotate()
+		assert.True(t, errors.Is(err, rotatelogs.ErrRotateLocked), "error should match ErrRotateLocked (got %v)", err)
+
+		var pathErr *os.PathError
+		assert.True(t, errors.As(err, &pathErr), "error should retain its cause (got %v)", err)
+	})
+
+	t.Run("ErrClosed", func(t *testing.T) {
+		rl, err := rotatelogs.New(filepath.Join(dir, "closed.log"))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		rl.Close()
+
+		_, err = rl.Write([]byte("Hello, World!"))
+		assert.True(t, errors.Is(err, rotatelogs.ErrClosed), "error should match ErrClosed (got %v)", err)
+	})
+}
+
+func TestReconfigure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-reconfigure")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	baseFn := filepath.Join(dir, "log")
+	rl, err := rotatelogs.New(baseFn, rotatelogs.WithLinkName(filepath.Join(dir, "old_link")))
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+	defer rl.Close()
+
+	rl.Write([]byte("Hello, World!"))
+
+	t.Run("RotationSize", func(t *testing.T) {
+		if !assert.NoError(t, rl.Reconfigure(rotatelogs.WithRotationSizeString("10B")), "rl.Reconfigure should succeed") {
+			return
+		}
+
+		rl.Write([]byte("Hello, World!"))
+		assert.Equal(t, baseFn+".1", rl.CurrentFileName(), "write should go to a new file")
+
+		content, err := ioutil.ReadFile(baseFn)
+		if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			return
+		}
+		assert.Equal(t, "Hello, World!", string(content), "file should be kept open until the size limit is reached")
+	})
+
+	t.Run("LinkName", func(t *testing.T) {
+		newLink := filepath.Join(dir, "new_link")
+		if !assert.NoError(t, rl.Reconfigure(rotatelogs.WithLinkName(newLink)), "rl.Reconfigure should succeed") {
+			return
+		}
+
+		linkDest, err := os.Readlink(newLink)
+		if !assert.NoError(t, err, "os.Readlink should succeed") {
+			return
+		}
+		assert.Equal(t, "log.1", linkDest, "link should point to the current file")
+
+		_, err = os.Lstat(filepath.Join(dir, "old_link"))
+		assert.True(t, os.IsNotExist(err), "old link should be removed")
+	})
+
+	t.Run("LinkName with placeholders", func(t *testing.T) {
+		if !assert.NoError(t, rl.Reconfigure(rotatelogs.WithLinkName(filepath.Join(dir, "%{pid}.link"))), "rl.Reconfigure should succeed") {
+			return
+		}
+
+		linkDest, err := os.Readlink(filepath.Join(dir, fmt.Sprintf("%d.link", os.Getpid())))
+		if !assert.NoError(t, err, "os.Readlink should succeed") {
+			return
+		}
+		assert.Equal(t, "log.1", linkDest, "link should point to the current file")
+
+		err = rl.Reconfigure(rotatelogs.WithLinkName(filepath.Join(dir, "%{unknown}.link")))
+		assert.True(t, errors.Is(err, rotatelogs.ErrInvalidPattern), "error should match ErrInvalidPattern (got %v)", err)
+	})
+
+	t.Run("LinkName failure", func(t *testing.T) {
+		// The link cannot be created underneath a regular file
+		err := rl.Reconfigure(rotatelogs.WithRotationSize(1024), rotatelogs.WithLinkName(filepath.Join(baseFn, "link")))
+		if !assert.Error(t, err, "rl.Reconfigure should fail") {
+			return
+		}
+
+		_, err = os.Readlink(filepath.Join(dir, fmt.Sprintf("%d.link", os.Getpid())))
+		assert.NoError(t, err, "old link should be kept")
+		_, err = os.Lstat(baseFn + ".1_symlink")
+		assert.True(t, os.IsNotExist(err), "temporary link should be removed")
+
+		rl.Write([]byte("Hello, World!"))
+		assert.Equal(t, baseFn+".2", rl.CurrentFileName(), "rotation size should not be changed")
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		assert.NoError(t, rl.Reconfigure(rotatelogs.WithRotationCount(3)), "switching to RotationCount should succeed")
+		assert.NoError(t, rl.Reconfigure(rotatelogs.WithMaxAgeString("30d")), "switching to MaxAge should succeed")
+
+		err := rl.Reconfigure(rotatelogs.WithMaxAge(time.Hour), rotatelogs.WithRotationCount(1))
+		assert.True(t, errors.Is(err, rotatelogs.ErrConflictingOptions), "error should match ErrConflictingOptions (got %v)", err)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		err := rl.Reconfigure(rotatelogs.WithRotationTime(time.Hour), rotatelogs.WithClock(rotatelogs.UTC))
+		assert.Error(t, err, "rl.Reconfigure should fail for options that cannot be changed")
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		rl.Close()
+		err := rl.Reconfigure(rotatelogs.WithMaxAge(time.Hour))
+		assert.True(t, errors.Is(err, rotatelogs.ErrClosed), "error should match ErrClosed (got %v)", err)
+	})
+}
+
+func TestStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-stats")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dummyTime := time.Now().Add(-7 * 24 * time.Hour)
+	clock := clockwork.NewFakeClockAt(dummyTime)
+	rl, err := rotatelogs.New(
+		filepath.Join(dir, "log"),
+		rotatelogs.WithClock(clock),
+		rotatelogs.WithMaxAge(-1),
+		rotatelogs.WithRotationCount(1),
+	)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+
+	st := rl.Stats()
+	assert.Equal(t, rotatelogs.Stats{}, st, "stats should be zero before the first write")
+
+	rl.Write([]byte("Hello, World!"))
+	st = rl.Stats()
+	assert.Equal(t, uint64(1), st.Writes, "Writes should match")
+	assert.Equal(t, uint64(13), st.BytesWritten, "BytesWritten should match")
+	assert.Equal(t, int64(13), st.CurrentFileSize, "CurrentFileSize should match")
+	assert.Equal(t, uint64(0), st.Rotations, "opening the first file should not count as a rotation")
+
+	clock.Advance(time.Minute)
+	for i := 0; i < 2; i++ {
+		if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+			return
+		}
+		rl.Write([]byte("Hello"))
+	}
+
+	if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+		return
+	}
+
+	st = rl.Stats()
+	assert.Equal(t, uint64(3), st.Writes, "Writes should match")
+	assert.Equal(t, uint64(23), st.BytesWritten, "BytesWritten should match")
+	assert.Equal(t, uint64(2), st.Rotations, "Rotations should match")
+	assert.Equal(t, 2, st.CurrentGeneration, "CurrentGeneration should match")
+	assert.True(t, st.LastRotation.Equal(clock.Now()), "LastRotation should use the clock (got %s)", st.LastRotation)
+	assert.Equal(t, uint64(2), st.Purges, "Purges should match")
+	assert.Equal(t, uint64(0), st.PurgeFailures, "PurgeFailures should match")
+	assert.True(t, st.WriteLatency > 0, "WriteLatency should be positive")
+
+	rl.Write([]byte("Hello"))
+	st = rl.Stats()
+	assert.Equal(t, uint64(4), st.Writes, "failed writes should be counted")
+	assert.Equal(t, uint64(1), st.WriteErrors, "WriteErrors should match")
+}
+
+type recordingInstrumentation struct {
+	mutex   sync.Mutex
+	writes  []rotatelogs.WriteInfo
+	rotates []rotatelogs.RotateInfo
+	purges  []rotatelogs.PurgeInfo
+}
+
+func (r *recordingInstrumentation) OnWrite(info rotatelogs.WriteInfo) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.writes = append(r.writes, info)
+}
+
+func (r *recordingInstrumentation) OnRotate(info rotatelogs.RotateInfo) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.rotates = append(r.rotates, info)
+}
+
+func (r *recordingInstrumentation) OnPurge(info rotatelogs.PurgeInfo) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.purges = append(r.purges, info)
+}
+
+func TestInstrumentation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-instrumentation")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var instr recordingInstrumentation
+	baseFn := filepath.Join(dir, "log")
+	rl, err := rotatelogs.New(
+		baseFn,
+		rotatelogs.WithInstrumentation(&instr),
+		rotatelogs.WithMaxAge(-1),
+		rotatelogs.WithRotationCount(1),
+	)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+
+	rl.Write([]byte("Hello, World!"))
+	if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+		return
+	}
+	rl.Write([]byte("Hello"))
+	if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+		return
+	}
+	rl.Write([]byte("Hello"))
+
+	instr.mutex.Lock()
+	defer instr.mutex.Unlock()
+
+	if assert.Len(t, instr.writes, 3, "OnWrite should be called for each write") {
+		assert.Equal(t, 13, instr.writes[0].Bytes, "Bytes should match")
+		assert.NoError(t, instr.writes[0].Err, "first write should succeed")
+		assert.False(t, instr.writes[0].Start.IsZero(), "Start should be set")
+		assert.True(t, errors.Is(instr.writes[2].Err, rotatelogs.ErrClosed), "Err should be reported (got %v)", instr.writes[2].Err)
+	}
+
+	if assert.Len(t, instr.rotates, 1, "OnRotate should be called once") {
+		assert.Equal(t, baseFn, instr.rotates[0].PreviousFile, "PreviousFile should match")
+		assert.Equal(t, baseFn+".1", instr.rotates[0].CurrentFile, "CurrentFile should match")
+		assert.NoError(t, instr.rotates[0].Err, "rotation should succeed")
+	}
+
+	if assert.Len(t, instr.purges, 1, "OnPurge should be called once") {
+		assert.Equal(t, []string{baseFn}, instr.purges[0].Files, "Files should match")
+		assert.Equal(t, 0, instr.purges[0].Failures, "Failures should match")
+	}
+}
+
+func TestPatternVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-patternvars")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	hostname, err := os.Hostname()
+	if !assert.NoError(t, err, "os.Hostname should succeed") {
+		return
+	}
+
+	t.Run("Builtin", func(t *testing.T) {
+		rl, err := rotatelogs.New(filepath.Join(dir, "%{hostname}.%{pid}.log"))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("Hello, World!"))
+		expected := filepath.Join(dir, fmt.Sprintf("%s.%d.log", hostname, os.Getpid()))
+		assert.Equal(t, expected, rl.CurrentFileName(), "placeholders should be replaced")
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		// Values are used verbatim, even if they look like strftime
+		// verbs or glob patterns
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "%{app}.log"),
+			rotatelogs.WithPatternVar("app", func() string { return "100%Y*" }),
+			rotatelogs.WithLinkName(filepath.Join(dir, "%{app}.current")),
+			rotatelogs.WithRotationSize(1),
+			rotatelogs.WithMaxAge(-1),
+			rotatelogs.WithRotationCount(1),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		// a file that the glob pattern must not match
+		other := filepath.Join(dir, "100%Yother.log")
+		if !assert.NoError(t, ioutil.WriteFile(other, nil, 0644), "ioutil.WriteFile should succeed") {
+			return
+		}
+
+		for i := 0; i < 3; i++ {
+			rl.Write([]byte("Hello, World!"))
+		}
+		if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+			return
+		}
+
+		_, err = os.Stat(filepath.Join(dir, "100%Y*.log.2"))
+		assert.NoError(t, err, "current file should exist")
+		_, err = os.Lstat(filepath.Join(dir, "100%Y*.current"))
+		assert.NoError(t, err, "placeholders should be replaced in the link name")
+		_, err = os.Stat(filepath.Join(dir, "100%Y*.log"))
+		assert.True(t, os.IsNotExist(err), "old files should be purged")
+		_, err = os.Stat(other)
+		assert.NoError(t, err, "unrelated files should not be purged")
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := rotatelogs.New(filepath.Join(dir, "%{unknown}.log"))
+		assert.True(t, errors.Is(err, rotatelogs.ErrInvalidPattern), "error should match ErrInvalidPattern (got %v)", err)
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("failed to write")
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestMirror(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-mirror")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("Mirror", func(t *testing.T) {
+		var buf strings.Builder
+		rl, err := rotatelogs.New(
+			filepath.Join(dir, "mirror.log"),
+			rotatelogs.WithMirror(&buf),
+			rotatelogs.WithMirror(failingWriter{}),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		p := []byte("Hello, World!\n")
+		n, err := rl.Write(p)
+		assert.NoError(t, err, "failing mirrors should not fail writes")
+		assert.Equal(t, len(p), n, "all bytes should be written")
+		copy(p, "Goodbye")
+
+		if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+			return
+		}
+		assert.Equal(t, "Hello, World!\n", buf.String(), "writes should be mirrored")
+		assert.Equal(t, uint64(1), rl.Stats().MirrorErrors, "mirror errors should be counted")
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		w := blockingWriter{release: make(chan struct{})}
+		rl, err := rotatelogs.New(filepath.Join(dir, "drop.log"), rotatelogs.WithMirror(w))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		for i := 0; i < 2000; i++ {
+			if _, err := rl.Write([]byte("Hello, World!\n")); !assert.NoError(t, err, "blocked mirrors should not block writes") {
+				return
+			}
+		}
+		close(w.release)
+
+		if !assert.NoError(t, rl.Shutdown(context.Background()), "rl.Shutdown should succeed") {
+			return
+		}
+		assert.True(t, rl.Stats().MirrorDrops > 0, "writes should be dropped when the mirror falls behind")
+	})
+}
+
+func TestLineAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-lineatomic")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	baseFn := filepath.Join(dir, "log")
+	rl, err := rotatelogs.New(
+		baseFn,
+		rotatelogs.WithLineAtomic(),
+		rotatelogs.WithRotationSize(10),
+		rotatelogs.WithOversizePolicy(rotatelogs.OversizeAllow),
+	)
+	if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+		return
+	}
+
+	assertContent := func(t *testing.T, filename, expected string) {
+		t.Helper()
+		content, err := ioutil.ReadFile(filename)
+		if assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			assert.Equal(t, expected, string(content), "content of %s should match", filename)
+		}
+	}
+
+	for _, p := range []string{"foo", "bar\nba"} {
+		n, err := rl.Write([]byte(p))
+		assert.NoError(t, err, "rl.Write should succeed")
+		assert.Equal(t, len(p), n, "incomplete lines should be reported as written")
+	}
+	assertContent(t, baseFn, "foobar\n")
+
+	// "baz\n" would make the file exceed 10 bytes
+	rl.Write([]byte("z\n"))
+	assertContent(t, baseFn, "foobar\n")
+	assertContent(t, baseFn+".1", "baz\n")
+
+	// writes larger than the rotation size go to a file of their own
+	rl.Write([]byte("a long line\n"))
+	assertContent(t, baseFn+".1", "baz\n")
+	assertContent(t, baseFn+".2", "a long line\n")
+
+	rl.Write([]byte("qux"))
+	if !assert.NoError(t, rl.Close(), "rl.Close should succeed") {
+		return
+	}
+	assertContent(t, baseFn+".3", "qux")
+}
+
+func TestOversizePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-oversize")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	assertContents := func(t *testing.T, baseFn string, expected ...string) {
+		t.Helper()
+		for i, e := range expected {
+			filename := baseFn
+			if i > 0 {
+				filename = fmt.Sprintf("%s.%d", baseFn, i)
+			}
+			content, err := ioutil.ReadFile(filename)
+			if assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+				assert.Equal(t, e, string(content), "content of %s should match", filename)
+			}
+		}
+		_, err := os.Stat(fmt.Sprintf("%s.%d", baseFn, len(expected)))
+		assert.True(t, os.IsNotExist(err), "there should be %d files", len(expected))
+	}
+
+	t.Run("SplitLines", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "split.log")
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithRotationSize(10),
+			rotatelogs.WithOversizePolicy(rotatelogs.OversizeSplitLines),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		p := "aaa\nbbb\nccc\n"
+		n, err := rl.Write([]byte(p))
+		assert.NoError(t, err, "rl.Write should succeed")
+		assert.Equal(t, len(p), n, "all bytes should be written")
+		assertContents(t, baseFn, "aaa\nbbb\n", "ccc\n")
+
+		rl.Write([]byte("a very long line\nd\n"))
+		assertContents(t, baseFn, "aaa\nbbb\n", "ccc\n", "a very long line\n", "d\n")
+	})
+
+	t.Run("Allow", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "allow.log")
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithRotationSize(10),
+			rotatelogs.WithOversizePolicy(rotatelogs.OversizeAllow),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("aaa\nbbb\nccc\n"))
+		rl.Write([]byte("d\n"))
+		rl.Write([]byte("e\n"))
+		assertContents(t, baseFn, "aaa\nbbb\nccc\n", "d\ne\n")
+	})
+
+	t.Run("CopyTruncate", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "copytruncate.log")
+		stableFn := filepath.Join(dir, "copytruncate.current")
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithCopyTruncate(stableFn),
+			rotatelogs.WithRotationSize(10),
+			rotatelogs.WithOversizePolicy(rotatelogs.OversizeAllow),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("aaaaaaaa\n"))
+		rl.Write([]byte("b\n"))
+		rl.Write([]byte("c\n"))
+		assertContents(t, baseFn, "aaaaaaaa\n")
+
+		content, err := ioutil.ReadFile(stableFn)
+		if assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			assert.Equal(t, "b\nc\n", string(content), "size should be tracked across truncation")
+		}
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "restart.log")
+		for i := 0; i < 2; i++ {
+			rl, err := rotatelogs.New(
+				baseFn,
+				rotatelogs.WithRotationSize(20),
+				rotatelogs.WithOversizePolicy(rotatelogs.OversizeAllow),
+			)
+			if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+				return
+			}
+			rl.Write([]byte("Hello, World! 42\n"))
+			if !assert.NoError(t, rl.Close(), "rl.Close should succeed") {
+				return
+			}
+		}
+		assertContents(t, baseFn, "Hello, World! 42\n", "Hello, World! 42\n")
+	})
+}
+
+func TestFileHeaderFooter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-header")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	header := func(fi rotatelogs.FileInfo) []byte {
+		return []byte(fmt.Sprintf("#Header: %s from %s\n", filepath.Base(fi.Filename), filepath.Base(fi.PreviousFile)))
+	}
+	footer := func(fi rotatelogs.FileInfo) []byte {
+		return []byte(fmt.Sprintf("#Footer: %s to %s\n", filepath.Base(fi.Filename), filepath.Base(fi.NextFile)))
+	}
+
+	assertContent := func(t *testing.T, filename, expected string) {
+		t.Helper()
+		content, err := ioutil.ReadFile(filename)
+		if assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			assert.Equal(t, expected, string(content), "content of %s should match", filename)
+		}
+	}
+
+	t.Run("Direct", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "direct.log")
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithFileHeader(header),
+			rotatelogs.WithFileFooter(footer),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+
+		rl.Write([]byte("a\n"))
+		if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+			return
+		}
+		rl.Write([]byte("b\n"))
+		rl.Close()
+
+		assertContent(t, baseFn, "#Header: direct.log from .\na\n#Footer: direct.log to direct.log.1\n")
+		assertContent(t, baseFn+".1", "#Header: direct.log.1 from direct.log\nb\n")
+
+		// Appending to an existing file does not repeat the header
+		rl, err = rotatelogs.New(
+			baseFn,
+			rotatelogs.WithFileHeader(header),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		rl.Write([]byte("c\n"))
+		rl.Close()
+
+		assertContent(t, baseFn, "#Header: direct.log from .\na\n#Footer: direct.log to direct.log.1\nc\n")
+	})
+
+	t.Run("RenameOnRotate", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "rename.log")
+		stableFn := filepath.Join(dir, "rename.current")
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithRenameOnRotate(stableFn),
+			rotatelogs.WithFileHeader(header),
+			rotatelogs.WithFileFooter(footer),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("a\n"))
+		if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+			return
+		}
+		rl.Write([]byte("b\n"))
+
+		assertContent(t, baseFn, "#Header: rename.current from .\na\n#Footer: rename.log to rename.current\n")
+		assertContent(t, stableFn, "#Header: rename.current from rename.log\nb\n")
+	})
+
+	t.Run("OversizePolicy", func(t *testing.T) {
+		short := func(text string) func(rotatelogs.FileInfo) []byte {
+			return func(rotatelogs.FileInfo) []byte { return []byte(text) }
+		}
+
+		baseFn := filepath.Join(dir, "oversize.log")
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithRotationSize(14),
+			rotatelogs.WithOversizePolicy(rotatelogs.OversizeSplitLines),
+			rotatelogs.WithFileHeader(short("#H\n")),
+			rotatelogs.WithFileFooter(short("#F\n")),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("aaa\nbbb\nccc\nddd\neee\n"))
+		rl.Write([]byte("a very long line\n"))
+
+		// Headers and footers count toward the rotation size
+		assertContent(t, baseFn, "#H\naaa\nbbb\n#F\n")
+		assertContent(t, baseFn+".1", "#H\nccc\nddd\n#F\n")
+		assertContent(t, baseFn+".2", "#H\neee\n#F\n")
+		assertContent(t, baseFn+".3", "#H\na very long line\n")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := os.Stat("/dev/full"); err != nil {
+			t.Skip("/dev/full is not available")
+		}
+
+		// Writes to the first file fail, and so does its footer
+		baseFn := filepath.Join(dir, "full.log")
+		if !assert.NoError(t, os.Symlink("/dev/full", baseFn), "os.Symlink should succeed") {
+			return
+		}
+
+		rl, err := rotatelogs.New(
+			baseFn,
+			rotatelogs.WithFileFooter(footer),
+		)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		rl.Write([]byte("a\n"))
+		assert.Error(t, rl.Rotate(), "rl.Rotate should fail when the footer cannot be written")
+		assert.Equal(t, baseFn+".1", rl.CurrentFileName(), "file should have been rotated regardless")
+	})
+}
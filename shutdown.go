@@ -0,0 +1,116 @@
+package rotatelogs
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/lestrrat-go/file-rotatelogs/internal/fileutil"
+	"github.com/pkg/errors"
+)
+
+// Shutdown gracefully closes the RotateLogs object. Unlike Close, it
+// makes sure that the data written so far is safely stored on disk
+// before returning:
+//
+// The current file is fsync'ed and closed, and its parent directory is
+// fsync'ed so that the file itself survives a crash. Then Shutdown waits
+// for background operations, such as purging old files and event
+// handlers, to finish, or until ctx is done.
+//
+// All errors that occur along the way are combined into the returned error.
+// As with Close, the object cannot be written to anymore afterwards.
+// Calling Shutdown on an object that has already been closed only waits
+// for background operations.
+func (rl *RotateLogs) Shutdown(ctx context.Context) error {
+	var errs multiError
+
+	rl.mutex.Lock()
+	if err := rl.flushPartialNolock(); err != nil {
+		errs = append(errs, err)
+	}
+	rl.markClosedNolock()
+	rl.stopSyncNolock()
+	rl.stopMirrorsNolock()
+
+	if rl.lockFh != nil {
+		if err := rl.lockFh.Close(); err != nil {
+			errs = append(errs, errors.Wrap(err, `failed to close process lock file`))
+		}
+		rl.lockFh = nil
+	}
+
+	if fh := rl.outFh; fh != nil {
+		if err := fh.Sync(); err != nil {
+			errs = append(errs, errors.Wrapf(err, `failed to sync %s`, fh.Name()))
+		}
+		if err := fh.Close(); err != nil {
+			errs = append(errs, errors.Wrapf(err, `failed to close %s`, fh.Name()))
+		}
+		rl.outFh = nil
+
+		if err := fileutil.SyncDir(filepath.Dir(fh.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	rl.mutex.Unlock()
+
+	// Background operations may need to acquire the lock (e.g. event
+	// handlers calling CurrentFileName), so wait without holding it
+	done := make(chan struct{})
+	go func() {
+		rl.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, errors.Wrap(ctx.Err(), `failed to wait for background operations`))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// multiError combines multiple errors into one
+type multiError []error
+
+func (e multiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the combined errors, for use with errors.Is and errors.As
+func (e multiError) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the combined errors matches target. It makes
+// errors.Is work on versions of Go that do not support Unwrap() []error
+func (e multiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the combined errors that matches target. It makes
+// errors.As work on versions of Go that do not support Unwrap() []error
+func (e multiError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package rotatelogs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// RotateOnSignal starts a goroutine that calls Rotate() every time
+// the process receives one of the given signals, until ctx is canceled
+// or the RotateLogs object is closed.
+// If no signals are given, SIGHUP is used.
+func (rl *RotateLogs) RotateOnSignal(ctx context.Context, sigs ...os.Signal) {
+	rl.onSignal(ctx, rl.Rotate, sigs)
+}
+
+// ReopenOnSignal starts a goroutine that calls Reopen() every time
+// the process receives one of the given signals, until ctx is canceled
+// or the RotateLogs object is closed.
+// If no signals are given, SIGHUP is used.
+//
+// This is what you want when the log files are rotated by an external
+// tool such as logrotate, which sends a signal to the process after
+// moving the files away (e.g. `postrotate kill -HUP`)
+func (rl *RotateLogs) ReopenOnSignal(ctx context.Context, sigs ...os.Signal) {
+	rl.onSignal(ctx, rl.Reopen, sigs)
+}
+
+func (rl *RotateLogs) onSignal(ctx context.Context, fn func() error, sigs []os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-rl.done:
+				return
+			case <-ch:
+				if err := fn(); err != nil {
+					if errors.Is(err, ErrClosed) {
+						return
+					}
+					fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,21 @@
+module github.com/lestrrat-go/file-rotatelogs/slogadapter
+
+go 1.21
+
+require (
+	github.com/lestrrat-go/file-rotatelogs v0.0.0-20261018234818-81f469e3573c
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/lestrrat-go/strftime v0.0.0-20180821113735-8b31f9c59b0f // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The root module is taken from this repository during development.
+// Users of this module get the version required above instead, since
+// replace directives only apply to the main module (see RELEASING.md)
+replace github.com/lestrrat-go/file-rotatelogs => ../
@@ -0,0 +1,185 @@
+// Package slogadapter provides a log/slog Handler that writes records
+// into a RotateLogs object.
+//
+//	rl, err := rotatelogs.New("/var/log/myapp/log.%Y%m%d")
+//	if err != nil {
+//		return err
+//	}
+//	logger := slog.New(slogadapter.New(rl, nil))
+//
+// To log a record whenever the file is rotated, pass a RotationEvents
+// to both rotatelogs.WithHandler and Options:
+//
+//	events := &slogadapter.RotationEvents{}
+//	rl, err := rotatelogs.New("/var/log/myapp/log.%Y%m%d", rotatelogs.WithHandler(events))
+//	if err != nil {
+//		return err
+//	}
+//	logger := slog.New(slogadapter.New(rl, &slogadapter.Options{RotationEvents: events}))
+package slogadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+)
+
+// Format is the format records are written in
+type Format int
+
+const (
+	// JSON writes records as JSON objects, one per line
+	JSON Format = iota
+	// Text writes records as key=value pairs, one per line
+	Text
+)
+
+// Options configure a Handler. The zero value writes JSON records
+// without flushing.
+type Options struct {
+	// Format is the format records are written in
+	Format Format
+
+	// HandlerOptions are passed to the underlying slog handler. May be nil
+	HandlerOptions *slog.HandlerOptions
+
+	// Sync makes the handler call RotateLogs.Sync after each record, so
+	// that records are stored on disk before Handle returns
+	Sync bool
+
+	// RotationEvents makes the handler write a "log file rotated" record
+	// to each new file. It must also be given to rotatelogs.WithHandler
+	// when creating the RotateLogs object. May be nil
+	RotationEvents *RotationEvents
+}
+
+// Handler is a slog.Handler that writes to a RotateLogs object
+type Handler struct {
+	inner  slog.Handler
+	shared *shared
+}
+
+// shared is the state shared by a Handler and the handlers derived from
+// it with WithAttrs and WithGroup
+type shared struct {
+	rl      *rotatelogs.RotateLogs
+	options Options
+}
+
+// RotationEvents is a rotatelogs.Handler that writes an info level
+// record with the message "log file rotated" whenever the file is
+// rotated, whose "file" and "previous_file" attributes hold the file
+// names. The record is written in the background as soon as the file
+// has been rotated, usually before any other record goes to the new
+// file. See Options.
+type RotationEvents struct {
+	mutex   sync.Mutex
+	handler slog.Handler
+	shared  *shared
+}
+
+var _ rotatelogs.Handler = (*RotationEvents)(nil)
+
+// Handle implements rotatelogs.Handler
+func (e *RotationEvents) Handle(ev rotatelogs.Event) {
+	rotated, ok := ev.(*rotatelogs.FileRotatedEvent)
+	if !ok || rotated.PreviousFile() == "" {
+		// not a rotation, but the very first file
+		return
+	}
+
+	e.mutex.Lock()
+	h, s := e.handler, e.shared
+	e.mutex.Unlock()
+
+	ctx := context.Background()
+	if h == nil || !h.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "log file rotated", 0)
+	r.AddAttrs(
+		slog.String("file", rotated.CurrentFile()),
+		slog.String("previous_file", rotated.PreviousFile()),
+	)
+	if err := s.handle(ctx, h, r); err != nil && !errors.Is(err, rotatelogs.ErrClosed) {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+}
+
+func (e *RotationEvents) attach(h slog.Handler, s *shared) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.handler = h
+	e.shared = s
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// New creates a Handler that writes to rl. opts may be nil.
+func New(rl *rotatelogs.RotateLogs, opts *Options) *Handler {
+	var options Options
+	if opts != nil {
+		options = *opts
+	}
+
+	var inner slog.Handler
+	switch options.Format {
+	case Text:
+		inner = slog.NewTextHandler(rl, options.HandlerOptions)
+	default:
+		inner = slog.NewJSONHandler(rl, options.HandlerOptions)
+	}
+
+	s := &shared{
+		rl:      rl,
+		options: options,
+	}
+	if options.RotationEvents != nil {
+		options.RotationEvents.attach(inner, s)
+	}
+
+	return &Handler{
+		inner:  inner,
+		shared: s,
+	}
+}
+
+// Enabled implements slog.Handler
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	return h.shared.handle(ctx, h.inner, r)
+}
+
+// handle writes r using h, and syncs it if requested
+func (s *shared) handle(ctx context.Context, h slog.Handler, r slog.Record) error {
+	if err := h.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	if s.options.Sync {
+		return s.rl.Sync()
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{inner: h.inner.WithAttrs(attrs), shared: h.shared}
+}
+
+// WithGroup implements slog.Handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name), shared: h.shared}
+}
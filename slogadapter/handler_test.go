@@ -0,0 +1,153 @@
+package slogadapter_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/lestrrat-go/file-rotatelogs/slogadapter"
+	"github.com/stretchr/testify/assert"
+)
+
+func readRecords(t *testing.T, filename string) []map[string]interface{} {
+	t.Helper()
+
+	f, err := os.Open(filename)
+	if !assert.NoError(t, err, "os.Open should succeed") {
+		return nil
+	}
+	defer f.Close()
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if !assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "json.Unmarshal should succeed") {
+			return nil
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-slogadapter")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("JSON", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "json.log")
+		rl, err := rotatelogs.New(baseFn)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		logger := slog.New(slogadapter.New(rl, &slogadapter.Options{Sync: true}))
+		logger.With("tenant", "acme").WithGroup("req").Info("Hello, World!", "id", 1)
+
+		records := readRecords(t, baseFn)
+		if assert.Len(t, records, 1, "one record should be written") {
+			assert.Equal(t, "Hello, World!", records[0]["msg"], "message should match")
+			assert.Equal(t, "acme", records[0]["tenant"], "attributes should match")
+			assert.Equal(t, map[string]interface{}{"id": float64(1)}, records[0]["req"], "groups should match")
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "text.log")
+		rl, err := rotatelogs.New(baseFn)
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		logger := slog.New(slogadapter.New(rl, &slogadapter.Options{
+			Format:         slogadapter.Text,
+			HandlerOptions: &slog.HandlerOptions{Level: slog.LevelWarn},
+		}))
+		logger.Info("ignored")
+		logger.Warn("Hello, World!")
+
+		content, err := ioutil.ReadFile(baseFn)
+		if !assert.NoError(t, err, "ioutil.ReadFile should succeed") {
+			return
+		}
+		assert.Equal(t, 1, strings.Count(string(content), "\n"), "records below the level should be ignored")
+		assert.Contains(t, string(content), `msg="Hello, World!"`, "records should be written as text")
+	})
+
+	t.Run("RotationEvents", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "rotation.log")
+		events := &slogadapter.RotationEvents{}
+		rl, err := rotatelogs.New(baseFn, rotatelogs.WithHandler(events))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		logger := slog.New(slogadapter.New(rl, &slogadapter.Options{RotationEvents: events}))
+		logger.Info("first")
+		if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+			return
+		}
+
+		// The event is written in the background, without waiting for
+		// another record
+		assert.Eventually(t, func() bool {
+			content, err := ioutil.ReadFile(baseFn + ".1")
+			return err == nil && len(content) > 0
+		}, time.Second, 10*time.Millisecond, "the rotation event should be written")
+		logger.Info("second")
+
+		assert.Len(t, readRecords(t, baseFn), 1, "the first file should only contain the first record")
+
+		records := readRecords(t, baseFn+".1")
+		if assert.Len(t, records, 2, "the new file should contain the rotation event and the record") {
+			assert.Equal(t, "log file rotated", records[0]["msg"], "message should match")
+			assert.Equal(t, baseFn+".1", records[0]["file"], "file should match")
+			assert.Equal(t, baseFn, records[0]["previous_file"], "previous_file should match")
+			assert.Equal(t, "second", records[1]["msg"], "message should match")
+		}
+	})
+
+	t.Run("RotationEventsLevel", func(t *testing.T) {
+		baseFn := filepath.Join(dir, "level.log")
+		events := &slogadapter.RotationEvents{}
+		handled := make(chan struct{}, 2)
+		rl, err := rotatelogs.New(baseFn, rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+			events.Handle(e)
+			handled <- struct{}{}
+		})))
+		if !assert.NoError(t, err, `rotatelogs.New should succeed`) {
+			return
+		}
+		defer rl.Close()
+
+		logger := slog.New(slogadapter.New(rl, &slogadapter.Options{
+			HandlerOptions: &slog.HandlerOptions{Level: slog.LevelWarn},
+			RotationEvents: events,
+		}))
+		logger.Warn("first")
+		if !assert.NoError(t, rl.Rotate(), "rl.Rotate should succeed") {
+			return
+		}
+		// one event for the first file, and one for the rotation
+		<-handled
+		<-handled
+
+		_, err = os.Stat(baseFn + ".1")
+		if assert.NoError(t, err, "os.Stat should succeed") {
+			assert.Empty(t, readRecords(t, baseFn+".1"), "no rotation event should be written below the handler level")
+		}
+	})
+}
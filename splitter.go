@@ -0,0 +1,163 @@
+package rotatelogs
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// LevelSplitter writes to several files, such as one for errors and one
+// for access logs, which are rotated together: whenever one of them is
+// rotated, for whatever reason, the others are rotated as well, so that
+// for example app.err.2024050100 and app.out.2024050100 always cover the
+// same period. Old files are removed by a single background goroutine.
+type LevelSplitter struct {
+	mutex   sync.Mutex // serializes writes across all members
+	names   []string
+	members map[string]*RotateLogs
+	purger  *purger
+}
+
+// NewLevelSplitter creates a LevelSplitter. patterns maps the name of
+// each writer, such as "err" or "out", to its file name pattern. The
+// options are applied to all writers. The "%{level}" placeholder is
+// defined as the name of each writer, so that for example a link name
+// may be given as WithLinkName("/var/log/app.%{level}").
+func NewLevelSplitter(patterns map[string]string, options ...Option) (*LevelSplitter, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("at least one pattern must be given")
+	}
+
+	s := &LevelSplitter{
+		members: make(map[string]*RotateLogs, len(patterns)),
+	}
+
+	for name, p := range patterns {
+		name := name
+		memberOptions := append([]Option{}, options...)
+		memberOptions = append(memberOptions, WithPatternVar("level", func() string { return name }))
+
+		rl, err := New(p, memberOptions...)
+		if err != nil {
+			for _, rl := range s.members {
+				rl.Close()
+			}
+			return nil, errors.Wrapf(err, `failed to create writer %#v`, name)
+		}
+		s.names = append(s.names, name)
+		s.members[name] = rl
+	}
+	sort.Strings(s.names)
+
+	s.purger = newPurger()
+	for _, rl := range s.members {
+		rl.purger = s.purger
+	}
+
+	return s, nil
+}
+
+// Writer returns the io.Writer for the given name. When a write rotates
+// its writer but the others cannot be rotated along, for example
+// because of ErrRotateLocked, the write returns their errors even
+// though the data has been written.
+func (s *LevelSplitter) Writer(name string) (io.Writer, error) {
+	if _, ok := s.members[name]; !ok {
+		return nil, errors.Errorf("unknown writer %#v", name)
+	}
+	return &splitterWriter{splitter: s, name: name}, nil
+}
+
+type splitterWriter struct {
+	splitter *LevelSplitter
+	name     string
+}
+
+func (w *splitterWriter) Write(p []byte) (int, error) {
+	return w.splitter.write(w.name, p)
+}
+
+func (s *LevelSplitter) write(name string, p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rl := s.members[name]
+	before := rl.stats.rotationsSoFar()
+	n, err := rl.Write(p)
+	if rl.stats.rotationsSoFar() == before {
+		return n, err
+	}
+
+	// This writer has just been rotated, so rotate the others as well.
+	// The data has been written regardless, so n is returned as is
+	var errs multiError
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, other := range s.names {
+		if other == name {
+			continue
+		}
+		if rerr := s.members[other].Rotate(); rerr != nil {
+			errs = append(errs, errors.Wrapf(rerr, `failed to rotate writer %#v`, other))
+		}
+	}
+
+	if len(errs) == 0 {
+		return n, nil
+	}
+	return n, errs
+}
+
+// Rotate forcefully rotates all writers. See RotateLogs.Rotate
+func (s *LevelSplitter) Rotate() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var errs multiError
+	for _, name := range s.names {
+		if err := s.members[name].Rotate(); err != nil {
+			errs = append(errs, errors.Wrapf(err, `failed to rotate writer %#v`, name))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// CurrentFileNames returns the file names currently being written to,
+// keyed by writer name. Writers that have not been written to yet are
+// mapped to an empty string.
+func (s *LevelSplitter) CurrentFileNames() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	filenames := make(map[string]string, len(s.members))
+	for name, rl := range s.members {
+		filenames[name] = rl.CurrentFileName()
+	}
+	return filenames
+}
+
+// Close closes all writers, and waits for pending purges to finish
+func (s *LevelSplitter) Close() error {
+	s.mutex.Lock()
+	var errs multiError
+	for _, name := range s.names {
+		if err := s.members[name].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.mutex.Unlock()
+
+	s.purger.stop()
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
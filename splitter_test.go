@@ -0,0 +1,111 @@
+package rotatelogs_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelSplitter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-rotatelogs-splitter")
+	if !assert.NoError(t, err, `creating temporary directory should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dummyTime := time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(dummyTime)
+	s, err := rotatelogs.NewLevelSplitter(
+		map[string]string{
+			"err": filepath.Join(dir, "app.err.%Y%m%d%H"),
+			"out": filepath.Join(dir, "app.out.%Y%m%d%H"),
+		},
+		rotatelogs.WithClock(clock),
+		rotatelogs.WithRotationTime(time.Hour),
+		rotatelogs.WithRotationSize(10),
+		rotatelogs.WithLinkName(filepath.Join(dir, "app.%{level}")),
+	)
+	if !assert.NoError(t, err, "rotatelogs.NewLevelSplitter should succeed") {
+		return
+	}
+	defer s.Close()
+
+	errW, err := s.Writer("err")
+	if !assert.NoError(t, err, "s.Writer should succeed") {
+		return
+	}
+	outW, err := s.Writer("out")
+	if !assert.NoError(t, err, "s.Writer should succeed") {
+		return
+	}
+	_, err = s.Writer("debug")
+	assert.Error(t, err, "s.Writer should fail for unknown names")
+
+	errW.Write([]byte("error"))
+	outW.Write([]byte("access"))
+	assert.Equal(t, map[string]string{
+		"err": filepath.Join(dir, "app.err.2024050100"),
+		"out": filepath.Join(dir, "app.out.2024050100"),
+	}, s.CurrentFileNames(), "file names should match")
+
+	t.Run("TimeRotation", func(t *testing.T) {
+		clock.Advance(time.Hour)
+		errW.Write([]byte("error"))
+		assert.Equal(t, map[string]string{
+			"err": filepath.Join(dir, "app.err.2024050101"),
+			"out": filepath.Join(dir, "app.out.2024050101"),
+		}, s.CurrentFileNames(), "writers should be rotated together")
+
+		linkDest, err := os.Readlink(filepath.Join(dir, "app.out"))
+		if assert.NoError(t, err, "os.Readlink should succeed") {
+			assert.Equal(t, "app.out.2024050101", linkDest, "each writer should have its own link")
+		}
+	})
+
+	t.Run("SizeRotation", func(t *testing.T) {
+		outW.Write([]byte("a long access log line"))
+		outW.Write([]byte("access"))
+		assert.Equal(t, map[string]string{
+			"err": filepath.Join(dir, "app.err.2024050101.1"),
+			"out": filepath.Join(dir, "app.out.2024050101.1"),
+		}, s.CurrentFileNames(), "writers should be rotated together")
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		if !assert.NoError(t, s.Rotate(), "s.Rotate should succeed") {
+			return
+		}
+		assert.Equal(t, map[string]string{
+			"err": filepath.Join(dir, "app.err.2024050101.2"),
+			"out": filepath.Join(dir, "app.out.2024050101.2"),
+		}, s.CurrentFileNames(), "writers should be rotated together")
+	})
+
+	t.Run("FailedRotation", func(t *testing.T) {
+		// A rotation lock held by somebody else makes the rotation of
+		// the "out" writer fail
+		lockFn := filepath.Join(dir, "app.out.2024050101.3_lock")
+		content := fmt.Sprintf("%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339Nano))
+		if !assert.NoError(t, ioutil.WriteFile(lockFn, []byte(content), 0644), "ioutil.WriteFile should succeed") {
+			return
+		}
+		defer os.Remove(lockFn)
+
+		errW.Write([]byte("a long error log line"))
+		n, err := errW.Write([]byte("error"))
+		assert.Equal(t, 5, n, "data should be written regardless")
+		assert.True(t, errors.Is(err, rotatelogs.ErrRotateLocked), "error should match ErrRotateLocked (got %v)", err)
+		assert.Equal(t, map[string]string{
+			"err": filepath.Join(dir, "app.err.2024050101.3"),
+			"out": filepath.Join(dir, "app.out.2024050101.2"),
+		}, s.CurrentFileNames(), "failed writer should keep its file")
+	})
+}
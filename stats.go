@@ -0,0 +1,151 @@
+package rotatelogs
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Stats is a snapshot of the counters kept by a RotateLogs object.
+// Counters start at zero when the object is created and are never
+// reset.
+type Stats struct {
+	// BytesWritten is the number of bytes successfully written
+	BytesWritten uint64
+	// Writes is the number of calls to Write, including failed ones
+	Writes uint64
+	// WriteErrors is the number of calls to Write that returned an error
+	WriteErrors uint64
+	// WriteLatency is the total time spent in Write, including the
+	// time spent waiting for other writers and rotating files. Divide
+	// it by Writes to get the average latency
+	WriteLatency time.Duration
+	// Rotations is the number of times a new file was started,
+	// not counting the very first file
+	Rotations uint64
+	// LastRotation is the time of the last rotation according to the
+	// clock of the RotateLogs object, or the zero time if no rotation
+	// has happened yet
+	LastRotation time.Time
+	// Purges is the number of old files that have been removed
+	Purges uint64
+	// PurgeFailures is the number of old files that could not be removed
+	PurgeFailures uint64
+	// CurrentFileSize is the size of the file currently being written to,
+	// or 0 if no file has been opened yet
+	CurrentFileSize int64
+	// CurrentGeneration is the generation of the file currently being
+	// written to. See WithGenerationFormat
+	CurrentGeneration int
+	// MirrorErrors is the number of writes that failed on a mirror.
+	// See WithMirror
+	MirrorErrors uint64
+	// MirrorDrops is the number of writes that were not copied to a
+	// mirror because it was falling behind
+	MirrorDrops uint64
+}
+
+// statsCounters holds the counters reported by Stats. It has its own
+// lock because purges run in the background without holding the lock
+// of the RotateLogs object
+type statsCounters struct {
+	mutex         sync.Mutex
+	bytesWritten  uint64
+	writes        uint64
+	writeErrors   uint64
+	writeLatency  time.Duration
+	rotations     uint64
+	lastRotation  time.Time
+	purges        uint64
+	purgeFailures uint64
+	mirrorErrors  uint64
+	mirrorDrops   uint64
+}
+
+func (c *statsCounters) recordWrite(n int, err error, latency time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.writes++
+	c.bytesWritten += uint64(n)
+	c.writeLatency += latency
+	if err != nil {
+		c.writeErrors++
+	}
+}
+
+func (c *statsCounters) recordRotation(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.rotations++
+	c.lastRotation = t
+}
+
+func (c *statsCounters) rotationsSoFar() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.rotations
+}
+
+func (c *statsCounters) recordPurge(err error) {
+	if os.IsNotExist(err) {
+		// removed by someone else, e.g. an earlier purge
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err != nil {
+		c.purgeFailures++
+		return
+	}
+	c.purges++
+}
+
+func (c *statsCounters) recordMirrorError() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.mirrorErrors++
+}
+
+func (c *statsCounters) recordMirrorDrop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.mirrorDrops++
+}
+
+// Stats returns a snapshot of the counters of the RotateLogs object.
+// It can be called at any time, including after Close.
+func (rl *RotateLogs) Stats() Stats {
+	rl.mutex.RLock()
+	var st Stats
+	if rl.outFh != nil {
+		if fi, err := rl.outFh.Stat(); err == nil {
+			st.CurrentFileSize = fi.Size()
+		}
+	}
+	st.CurrentGeneration = rl.generation
+	rl.mutex.RUnlock()
+
+	c := &rl.stats
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	st.BytesWritten = c.bytesWritten
+	st.Writes = c.writes
+	st.WriteErrors = c.writeErrors
+	st.WriteLatency = c.writeLatency
+	st.Rotations = c.rotations
+	st.LastRotation = c.lastRotation
+	st.Purges = c.purges
+	st.PurgeFailures = c.purgeFailures
+	st.MirrorErrors = c.mirrorErrors
+	st.MirrorDrops = c.mirrorDrops
+
+	return st
+}
@@ -0,0 +1,135 @@
+package rotatelogs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lestrrat-go/file-rotatelogs/internal/fileutil"
+	"github.com/pkg/errors"
+)
+
+type syncMode int
+
+const (
+	syncModeNever syncMode = iota
+	syncModeEveryWrite
+	syncModeInterval
+	syncModeOnRotate
+)
+
+// SyncPolicy determines when the RotateLogs object fsyncs the file
+// that it writes to. Use one of SyncNever, SyncEveryWrite, SyncOnRotate,
+// or SyncInterval().
+//
+// With any policy other than SyncNever, files are also fsync'ed before
+// they are closed upon rotation, and parent directories are fsync'ed
+// after creating files and symlinks in them.
+type SyncPolicy struct {
+	mode     syncMode
+	interval time.Duration
+}
+
+var (
+	// SyncNever leaves it up to the operating system to decide
+	// when data is written to disk. This is the default.
+	SyncNever = SyncPolicy{mode: syncModeNever}
+
+	// SyncEveryWrite fsyncs the file after every write.
+	SyncEveryWrite = SyncPolicy{mode: syncModeEveryWrite}
+
+	// SyncOnRotate fsyncs files only when they are closed upon rotation.
+	SyncOnRotate = SyncPolicy{mode: syncModeOnRotate}
+)
+
+// SyncInterval creates a SyncPolicy that fsyncs the file in the
+// background every interval, if it has been written to since.
+// The interval must be positive, otherwise New returns an error.
+func SyncInterval(d time.Duration) SyncPolicy {
+	return SyncPolicy{mode: syncModeInterval, interval: d}
+}
+
+// Sync fsyncs the file that the RotateLogs object is currently
+// writing to
+func (rl *RotateLogs) Sync() error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.closed {
+		return ErrClosed
+	}
+
+	return rl.syncNolock()
+}
+
+// must be locked during this operation
+func (rl *RotateLogs) syncNolock() error {
+	rl.dirty = false
+	if rl.outFh == nil {
+		return nil
+	}
+
+	if err := rl.outFh.Sync(); err != nil {
+		return errors.Wrapf(err, `failed to sync %s`, rl.outFh.Name())
+	}
+
+	return nil
+}
+
+// closeFileNolock closes a file that we are done writing to,
+// fsync'ing it first unless the sync policy is SyncNever
+//
+// must be locked during this operation
+func (rl *RotateLogs) closeFileNolock(fh *os.File) error {
+	if fh == nil {
+		return nil
+	}
+
+	if rl.syncPolicy.mode != syncModeNever {
+		if err := fh.Sync(); err != nil {
+			fh.Close()
+
+			return errors.Wrapf(err, `failed to sync %s`, fh.Name())
+		}
+	}
+
+	return fh.Close()
+}
+
+// syncDirNolock fsyncs the parent directory of the given path,
+// unless the sync policy is SyncNever
+//
+// must be locked during this operation
+func (rl *RotateLogs) syncDirNolock(path string) error {
+	if rl.syncPolicy.mode == syncModeNever {
+		return nil
+	}
+
+	return fileutil.SyncDir(filepath.Dir(path))
+}
+
+// runSyncInterval fsyncs the current file every interval, if it has
+// been written to since, until stop is closed
+func (rl *RotateLogs) runSyncInterval(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rl.mutex.Lock()
+			var err error
+			if rl.dirty {
+				err = rl.syncNolock()
+			}
+			rl.mutex.Unlock()
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+		}
+	}
+}